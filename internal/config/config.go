@@ -0,0 +1,155 @@
+// Package config loads the user's configuration file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the contents of the configuration file.
+type Config struct {
+	// Keys overrides the bindings of actions by name, e.g. "encounter.new".
+	Keys map[string]KeyBinding `yaml:"keys"`
+
+	// Theme is the name of the theme to use, either built in or one of
+	// Themes.
+	Theme string `yaml:"theme"`
+	// Themes are user-defined themes, by name.
+	Themes map[string]Theme `yaml:"themes"`
+
+	// TurnLimit is the turn limit new encounters start with, e.g. "1m30s".
+	TurnLimit time.Duration `yaml:"turn_limit"`
+
+	// System is the name of the game system new encounters follow by
+	// default, e.g. "pf2e".
+	System string `yaml:"system"`
+
+	// Monsters are added to the built-in monsters random encounters are
+	// generated from, replacing built-in monsters with the same name.
+	Monsters []Monster `yaml:"monsters"`
+	// BuiltinMonsters can be set to false to generate random encounters
+	// from Monsters alone.
+	BuiltinMonsters *bool `yaml:"builtin_monsters"`
+}
+
+// Monster is a monster random encounters can be generated with.
+type Monster struct {
+	Name string `yaml:"name"`
+	// System is the name of the game system the monster belongs to, or
+	// empty for the game system new encounters follow by default.
+	System string `yaml:"system"`
+	// Challenge is how dangerous the monster is: its challenge rating in
+	// D&D 5e, e.g. "1/4", or its level in Pathfinder 2e.
+	Challenge string `yaml:"challenge"`
+	// Environments are where the monster is found, e.g. "forest". A
+	// monster without any is found everywhere.
+	Environments []string `yaml:"environments"`
+	// Initiative is what the monster adds to initiative rolls: its
+	// Dexterity modifier in D&D 5e, or its Perception in Pathfinder 2e.
+	Initiative int `yaml:"initiative"`
+	// ArmorClass and StatBlock are given to the monster when it's added to
+	// an encounter. The stat block is written in Markdown.
+	ArmorClass int    `yaml:"armor_class"`
+	StatBlock  string `yaml:"stat_block"`
+}
+
+// KeyBinding overrides the keys bound to an action and its help text.
+type KeyBinding struct {
+	Keys []string `yaml:"keys"`
+	Help string   `yaml:"help"`
+}
+
+// Theme is a user-defined theme. Colors left empty are taken from the
+// default theme.
+type Theme struct {
+	Title      Color `yaml:"title"`
+	Initiative Color `yaml:"initiative"`
+	Selected   Color `yaml:"selected"`
+	Text       Color `yaml:"text"`
+	Subtle     Color `yaml:"subtle"`
+	Error      Color `yaml:"error"`
+}
+
+// Color is a terminal color given as an ANSI color number or a hex code. It
+// is either a single color, or a mapping with a color for light and for dark
+// terminals.
+type Color struct {
+	Light string `yaml:"light"`
+	Dark  string `yaml:"dark"`
+}
+
+func (c *Color) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Light = node.Value
+		c.Dark = node.Value
+		return nil
+	}
+
+	type color Color
+	return node.Decode((*color)(c))
+}
+
+// IsZero reports whether no color was given.
+func (c Color) IsZero() bool {
+	return c.Light == "" && c.Dark == ""
+}
+
+// Dir returns the directory holding the configuration file and other
+// per-user files.
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "initiative"), nil
+}
+
+// DefaultPath returns the path of the configuration file used when none is
+// given.
+func DefaultPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// DefaultDataPath returns the path of the file holding the party and
+// encounter history when none is given.
+func DefaultDataPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "data.yaml"), nil
+}
+
+// Load reads the configuration file at path. A missing file is not an error
+// and results in an empty configuration.
+func Load(path string) (Config, error) {
+	cfg := Config{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,175 @@
+// Package server shares the tracker with other devices, either as the live
+// initiative order over HTTP or as the full tracker over SSH.
+package server
+
+import (
+	"encoding/json"
+	"html/template"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"initiative/internal/ui"
+)
+
+// State is the JSON representation of the running encounter.
+type State struct {
+	Active    bool      `json:"active"`
+	Summary   string    `json:"summary"`
+	StartedAt time.Time `json:"started_at"`
+	Round     int       `json:"round"`
+	Turn      int       `json:"turn"`
+	Groups    []Group   `json:"groups"`
+	Waiting   []Waiting `json:"waiting"`
+}
+
+// Group is a single entry in the initiative order.
+type Group struct {
+	Initiative int      `json:"initiative"`
+	Side       string   `json:"side,omitempty"`
+	Creatures  []string `json:"creatures"`
+	Active     bool     `json:"active"`
+	// Acted reports whether the group has acted this round in popcorn
+	// initiative.
+	Acted bool `json:"acted,omitempty"`
+}
+
+// Waiting is a creature that left the initiative order to delay or ready an
+// action.
+type Waiting struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+}
+
+// NewState converts an encounter into its JSON representation. An encounter
+// without initiative groups is reported as inactive.
+func NewState(e ui.Encounter) State {
+	state := State{
+		Active:    len(e.IniativeGroups) > 0 || len(e.Waiting) > 0,
+		Summary:   e.Summary,
+		StartedAt: e.StartedAt,
+		Round:     e.Round,
+		Turn:      e.Turn,
+		Groups:    []Group{},
+		Waiting:   []Waiting{},
+	}
+
+	for i, group := range e.IniativeGroups {
+		names := []string{}
+		for _, creature := range group.Creatures {
+			names = append(names, creature.Name())
+		}
+		state.Groups = append(state.Groups, Group{
+			Initiative: group.Iniative,
+			Side:       group.Side,
+			Creatures:  names,
+			Active:     i == e.Turn,
+			Acted:      e.Mode == ui.PopcornInitiative && e.HasActed(i),
+		})
+	}
+
+	for _, waiting := range e.Waiting {
+		state.Waiting = append(state.Waiting, Waiting{
+			Name:   waiting.Creature.Name(),
+			Action: waiting.Action.String(),
+		})
+	}
+
+	return state
+}
+
+// Server serves the latest published encounter state.
+type Server struct {
+	mu    sync.RWMutex
+	state State
+}
+
+func New() *Server {
+	return &Server{state: State{Groups: []Group{}, Waiting: []Waiting{}}}
+}
+
+// Publish replaces the served state with the given encounter. It is safe to
+// use as a ui.WithEncounterObserver callback.
+func (s *Server) Publish(e ui.Encounter) {
+	state := NewState(e)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+}
+
+// State returns the most recently published state.
+func (s *Server) State() State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// Handler returns the HTTP handler serving the web page at "/" and the JSON
+// state at "/state.json".
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePage)
+	mux.HandleFunc("GET /state.json", s.handleState)
+	return mux
+}
+
+// Serve accepts HTTP connections on l until it is closed.
+func (s *Server) Serve(l net.Listener) error {
+	return http.Serve(l, s.Handler())
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(s.State())
+}
+
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	page.Execute(w, s.State())
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="2">
+<title>Initiative</title>
+<style>
+body { background: #1a1a1a; color: #d0d0d0; font-family: sans-serif; margin: 1.5rem; }
+h1 { color: #ff5fd7; font-size: 1.5rem; }
+h2 { color: #ffaf00; font-size: 1.1rem; }
+ol, ul { list-style: none; padding: 0; }
+li { padding: 0.75rem 1rem; margin-bottom: 0.5rem; border-left: 4px solid #444; }
+li.active { border-left-color: #d75fd7; background: #2a2a2a; color: #fff; }
+.initiative { color: #ffaf00; font-weight: bold; margin-right: 0.5rem; }
+.action, .side { color: #585858; }
+.empty { color: #585858; font-style: italic; }
+</style>
+</head>
+<body>
+{{if .Active}}
+<h1>{{.Summary}} &middot; Round {{.Round}}</h1>
+<ol>
+{{range .Groups}}
+<li{{if .Active}} class="active"{{end}}><span class="initiative">{{if gt .Initiative 0}}{{.Initiative}}{{else if .Acted}}&check;{{else}}TBD{{end}}</span>{{with .Side}}<span class="side">{{.}}:</span> {{end}}{{range $i, $name := .Creatures}}{{if $i}}, {{end}}{{$name}}{{end}}</li>
+{{end}}
+</ol>
+{{if .Waiting}}
+<h2>Waiting</h2>
+<ul>
+{{range .Waiting}}
+<li>{{.Name}} <span class="action">{{.Action}}</span></li>
+{{end}}
+</ul>
+{{end}}
+{{else}}
+<p class="empty">No encounter started...</p>
+{{end}}
+</body>
+</html>
+`))
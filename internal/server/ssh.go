@@ -0,0 +1,113 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	"github.com/charmbracelet/wish/activeterm"
+	"github.com/charmbracelet/wish/bubbletea"
+	"github.com/charmbracelet/wish/logging"
+	gossh "golang.org/x/crypto/ssh"
+
+	"initiative/internal/ui"
+)
+
+// SSHAccess says who may connect to the SSH server.
+type SSHAccess struct {
+	// DMKeys are the keys allowed to run the tracker.
+	DMKeys []ssh.PublicKey
+	// PlayerKeys are the keys allowed to watch the encounter.
+	PlayerKeys []ssh.PublicKey
+	// Anonymous lets anyone watch the encounter, with any key or none.
+	Anonymous bool
+}
+
+// NewSSHServer returns an SSH server sharing a single tracker between all of
+// its sessions. A session authenticated with one of the DM keys gets the
+// read-write tracker, one session at a time. Every other session allowed
+// in gets a read-only player view of the same encounter. opts apply to
+// every session.
+func NewSSHServer(addr, hostKeyPath string, access SSHAccess, opts ...ui.Option) (*ssh.Server, error) {
+	feed := ui.NewEncounterFeed()
+	tracker := ui.NewModel(append(opts, ui.WithEncounterObserver(feed.Publish))...)
+
+	// held for as long as a DM session is running the tracker
+	var dmSession sync.Mutex
+
+	handler := func(sess ssh.Session) (tea.Model, []tea.ProgramOption) {
+		if hasKey(access.DMKeys, sess.PublicKey()) && dmSession.TryLock() {
+			go func() {
+				<-sess.Context().Done()
+				dmSession.Unlock()
+			}()
+			return tracker, []tea.ProgramOption{tea.WithMouseCellMotion()}
+		}
+
+		view, unsubscribe := ui.NewPlayerView(feed, opts...)
+		go func() {
+			<-sess.Context().Done()
+			unsubscribe()
+		}()
+		return view, nil
+	}
+
+	return wish.NewServer(
+		wish.WithAddress(addr),
+		wish.WithHostKeyPath(hostKeyPath),
+		wish.WithPublicKeyAuth(func(_ ssh.Context, key ssh.PublicKey) bool {
+			return access.Anonymous || hasKey(access.DMKeys, key) || hasKey(access.PlayerKeys, key)
+		}),
+		// sessions without a key are only let in to watch anonymously
+		wish.WithKeyboardInteractiveAuth(func(ssh.Context, gossh.KeyboardInteractiveChallenge) bool {
+			return access.Anonymous
+		}),
+		wish.WithMiddleware(
+			bubbletea.Middleware(handler),
+			activeterm.Middleware(),
+			logging.Middleware(),
+		),
+	)
+}
+
+// hasKey reports whether key is one of keys.
+func hasKey(keys []ssh.PublicKey, key ssh.PublicKey) bool {
+	if key == nil {
+		return false
+	}
+	for _, k := range keys {
+		if ssh.KeysEqual(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadAuthorizedKeys reads the public keys from a file in the OpenSSH
+// authorized_keys format.
+func LoadAuthorizedKeys(path string) ([]ssh.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []ssh.PublicKey{}
+	for len(data) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			// ParseAuthorizedKey skips lines it can't parse and only fails
+			// once there are no keys left.
+			if len(keys) == 0 {
+				return nil, fmt.Errorf("no keys found in %s: %w", path, err)
+			}
+			break
+		}
+		keys = append(keys, key)
+		data = rest
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,341 @@
+package ui
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"initiative/internal/config"
+)
+
+// Bestiary holds the monsters random encounters are generated from.
+type Bestiary struct {
+	monsters []bestiaryMonster
+}
+
+// bestiaryMonster is a monster of a game system and where it's found.
+type bestiaryMonster struct {
+	name string
+	// system is the name of the game system the monster belongs to
+	system string
+	// challenge is how dangerous the monster is, as parsed by its game
+	// system
+	challenge string
+	// environments are where the monster is found, or empty when it's
+	// found everywhere
+	environments []string
+	// initiative, armorClass and statBlock are given to the monster in
+	// encounters
+	initiative int
+	armorClass int
+	statBlock  string
+}
+
+// builtinMonsters are the monsters random encounters are generated from
+// unless the configuration file says otherwise.
+var builtinMonsters = []bestiaryMonster{
+	{name: "Bandit", system: "5e", challenge: "1/8", environments: []string{"arctic", "coastal", "desert", "forest", "grassland", "hill", "urban"}},
+	{name: "Bandit Captain", system: "5e", challenge: "2", environments: []string{"coastal", "desert", "forest", "grassland", "hill", "urban"}},
+	{name: "Basilisk", system: "5e", challenge: "3", environments: []string{"mountain", "underdark"}},
+	{name: "Brown Bear", system: "5e", challenge: "1", environments: []string{"arctic", "forest", "hill"}},
+	{name: "Bugbear", system: "5e", challenge: "1", environments: []string{"forest", "grassland", "hill", "underdark"}},
+	{name: "Bullywug", system: "5e", challenge: "1/4", environments: []string{"swamp"}},
+	{name: "Crocodile", system: "5e", challenge: "1/2", environments: []string{"swamp"}},
+	{name: "Cultist", system: "5e", challenge: "1/8", environments: []string{"underdark", "urban"}},
+	{name: "Dire Wolf", system: "5e", challenge: "1", environments: []string{"forest", "hill"}},
+	{name: "Drow", system: "5e", challenge: "1/4", environments: []string{"underdark"}},
+	{name: "Duergar", system: "5e", challenge: "1", environments: []string{"underdark"}},
+	{name: "Frost Giant", system: "5e", challenge: "8", environments: []string{"arctic", "mountain"}},
+	{name: "Gargoyle", system: "5e", challenge: "2", environments: []string{"mountain", "underdark", "urban"}},
+	{name: "Gelatinous Cube", system: "5e", challenge: "2", environments: []string{"underdark"}},
+	{name: "Ghast", system: "5e", challenge: "2", environments: []string{"swamp", "underdark", "urban"}},
+	{name: "Ghoul", system: "5e", challenge: "1", environments: []string{"swamp", "underdark", "urban"}},
+	{name: "Giant Frog", system: "5e", challenge: "1/4", environments: []string{"forest", "swamp"}},
+	{name: "Giant Hyena", system: "5e", challenge: "1", environments: []string{"desert", "grassland"}},
+	{name: "Giant Octopus", system: "5e", challenge: "1", environments: []string{"underwater"}},
+	{name: "Giant Rat", system: "5e", challenge: "1/8", environments: []string{"forest", "swamp", "underdark", "urban"}},
+	{name: "Giant Scorpion", system: "5e", challenge: "3", environments: []string{"desert"}},
+	{name: "Giant Spider", system: "5e", challenge: "1", environments: []string{"forest", "underdark"}},
+	{name: "Gnoll", system: "5e", challenge: "1/2", environments: []string{"desert", "forest", "grassland", "hill"}},
+	{name: "Goblin", system: "5e", challenge: "1/4", environments: []string{"forest", "grassland", "hill", "underdark"}},
+	{name: "Green Hag", system: "5e", challenge: "3", environments: []string{"forest", "swamp"}},
+	{name: "Griffon", system: "5e", challenge: "2", environments: []string{"coastal", "grassland", "hill", "mountain"}},
+	{name: "Harpy", system: "5e", challenge: "1", environments: []string{"coastal", "mountain"}},
+	{name: "Hill Giant", system: "5e", challenge: "5", environments: []string{"hill", "mountain"}},
+	{name: "Hobgoblin", system: "5e", challenge: "1/2", environments: []string{"forest", "grassland", "hill"}},
+	{name: "Hook Horror", system: "5e", challenge: "3", environments: []string{"underdark"}},
+	{name: "Hunter Shark", system: "5e", challenge: "2", environments: []string{"underwater"}},
+	{name: "Jackal", system: "5e", challenge: "0", environments: []string{"desert", "grassland"}},
+	{name: "Kobold", system: "5e", challenge: "1/8", environments: []string{"forest", "hill", "mountain", "underdark", "urban"}},
+	{name: "Lion", system: "5e", challenge: "1", environments: []string{"desert", "grassland", "hill", "mountain"}},
+	{name: "Lizardfolk", system: "5e", challenge: "1/2", environments: []string{"swamp"}},
+	{name: "Manticore", system: "5e", challenge: "3", environments: []string{"arctic", "coastal", "grassland", "hill", "mountain"}},
+	{name: "Merrow", system: "5e", challenge: "2", environments: []string{"coastal", "underwater"}},
+	{name: "Minotaur", system: "5e", challenge: "3", environments: []string{"underdark"}},
+	{name: "Mummy", system: "5e", challenge: "3", environments: []string{"desert"}},
+	{name: "Ogre", system: "5e", challenge: "2", environments: []string{"arctic", "desert", "forest", "grassland", "hill", "mountain", "swamp", "underdark"}},
+	{name: "Orc", system: "5e", challenge: "1/2", environments: []string{"arctic", "forest", "grassland", "hill", "mountain", "swamp", "underdark"}},
+	{name: "Owlbear", system: "5e", challenge: "3", environments: []string{"forest"}},
+	{name: "Polar Bear", system: "5e", challenge: "2", environments: []string{"arctic"}},
+	{name: "Reef Shark", system: "5e", challenge: "1/2", environments: []string{"underwater"}},
+	{name: "Sahuagin", system: "5e", challenge: "1/2", environments: []string{"coastal", "underwater"}},
+	{name: "Skeleton", system: "5e", challenge: "1/4", environments: []string{"underdark", "urban"}},
+	{name: "Spy", system: "5e", challenge: "1", environments: []string{"urban"}},
+	{name: "Stone Giant", system: "5e", challenge: "7", environments: []string{"hill", "mountain", "underdark"}},
+	{name: "Thug", system: "5e", challenge: "1/2", environments: []string{"urban"}},
+	{name: "Troll", system: "5e", challenge: "5", environments: []string{"arctic", "forest", "hill", "mountain", "swamp", "underdark"}},
+	{name: "Veteran", system: "5e", challenge: "3", environments: []string{"hill", "urban"}},
+	{name: "Wight", system: "5e", challenge: "3", environments: []string{"swamp", "underdark", "urban"}},
+	{name: "Winter Wolf", system: "5e", challenge: "3", environments: []string{"arctic"}},
+	{name: "Wolf", system: "5e", challenge: "1/4", environments: []string{"arctic", "forest", "grassland", "hill"}},
+	{name: "Wyvern", system: "5e", challenge: "6", environments: []string{"hill", "mountain"}},
+	{name: "Yeti", system: "5e", challenge: "3", environments: []string{"arctic", "mountain"}},
+	{name: "Young Black Dragon", system: "5e", challenge: "7", environments: []string{"swamp"}},
+	{name: "Young Blue Dragon", system: "5e", challenge: "9", environments: []string{"coastal", "desert"}},
+	{name: "Young Green Dragon", system: "5e", challenge: "8", environments: []string{"forest"}},
+	{name: "Young White Dragon", system: "5e", challenge: "6", environments: []string{"arctic"}},
+	{name: "Zombie", system: "5e", challenge: "1/4", environments: []string{"swamp", "underdark", "urban"}},
+
+	{name: "Basilisk", system: "pf2e", challenge: "5", environments: []string{"mountain", "underdark"}},
+	{name: "Boar", system: "pf2e", challenge: "2", environments: []string{"forest", "grassland", "hill"}},
+	{name: "Bugbear Thug", system: "pf2e", challenge: "2", environments: []string{"forest", "hill", "underdark"}},
+	{name: "Crocodile", system: "pf2e", challenge: "2", environments: []string{"swamp"}},
+	{name: "Gargoyle", system: "pf2e", challenge: "4", environments: []string{"mountain", "urban"}},
+	{name: "Gelatinous Cube", system: "pf2e", challenge: "3", environments: []string{"underdark"}},
+	{name: "Ghast", system: "pf2e", challenge: "2", environments: []string{"swamp", "underdark", "urban"}},
+	{name: "Ghoul", system: "pf2e", challenge: "1", environments: []string{"swamp", "underdark", "urban"}},
+	{name: "Giant Crocodile", system: "pf2e", challenge: "6", environments: []string{"swamp"}},
+	{name: "Giant Rat", system: "pf2e", challenge: "-1", environments: []string{"forest", "swamp", "underdark", "urban"}},
+	{name: "Giant Scorpion", system: "pf2e", challenge: "3", environments: []string{"desert"}},
+	{name: "Gnoll Hunter", system: "pf2e", challenge: "2", environments: []string{"desert", "grassland", "hill"}},
+	{name: "Goblin Commando", system: "pf2e", challenge: "1", environments: []string{"forest", "hill", "underdark"}},
+	{name: "Goblin Warrior", system: "pf2e", challenge: "-1", environments: []string{"forest", "hill", "underdark"}},
+	{name: "Great White Shark", system: "pf2e", challenge: "4", environments: []string{"underwater"}},
+	{name: "Grizzly Bear", system: "pf2e", challenge: "3", environments: []string{"arctic", "forest", "hill"}},
+	{name: "Harpy", system: "pf2e", challenge: "5", environments: []string{"coastal", "mountain"}},
+	{name: "Hill Giant", system: "pf2e", challenge: "7", environments: []string{"hill", "mountain"}},
+	{name: "Hobgoblin Soldier", system: "pf2e", challenge: "1", environments: []string{"forest", "grassland", "hill"}},
+	{name: "Kobold Scout", system: "pf2e", challenge: "1", environments: []string{"hill", "mountain", "underdark"}},
+	{name: "Kobold Warrior", system: "pf2e", challenge: "-1", environments: []string{"hill", "mountain", "underdark"}},
+	{name: "Lizardfolk Defender", system: "pf2e", challenge: "2", environments: []string{"swamp"}},
+	{name: "Manticore", system: "pf2e", challenge: "6", environments: []string{"grassland", "hill", "mountain"}},
+	{name: "Merfolk Warrior", system: "pf2e", challenge: "1", environments: []string{"coastal", "underwater"}},
+	{name: "Minotaur", system: "pf2e", challenge: "4", environments: []string{"underdark"}},
+	{name: "Ogre Warrior", system: "pf2e", challenge: "3", environments: []string{"forest", "hill", "mountain", "swamp"}},
+	{name: "Orc Brute", system: "pf2e", challenge: "0", environments: []string{"forest", "grassland", "hill", "mountain"}},
+	{name: "Orc Warrior", system: "pf2e", challenge: "1", environments: []string{"forest", "grassland", "hill", "mountain"}},
+	{name: "Owlbear", system: "pf2e", challenge: "4", environments: []string{"forest"}},
+	{name: "Sahuagin", system: "pf2e", challenge: "2", environments: []string{"coastal", "underwater"}},
+	{name: "Sea Hag", system: "pf2e", challenge: "3", environments: []string{"coastal", "underwater"}},
+	{name: "Skeleton Guard", system: "pf2e", challenge: "-1", environments: []string{"underdark", "urban"}},
+	{name: "Troll", system: "pf2e", challenge: "5", environments: []string{"forest", "mountain", "swamp"}},
+	{name: "Warg", system: "pf2e", challenge: "2", environments: []string{"arctic", "forest", "hill"}},
+	{name: "Wight", system: "pf2e", challenge: "3", environments: []string{"swamp", "underdark", "urban"}},
+	{name: "Wolf", system: "pf2e", challenge: "1", environments: []string{"arctic", "forest", "grassland", "hill"}},
+	{name: "Wyvern", system: "pf2e", challenge: "6", environments: []string{"hill", "mountain"}},
+	{name: "Yeti", system: "pf2e", challenge: "5", environments: []string{"arctic", "mountain"}},
+	{name: "Young Green Dragon", system: "pf2e", challenge: "8", environments: []string{"forest"}},
+	{name: "Young White Dragon", system: "pf2e", challenge: "6", environments: []string{"arctic"}},
+	{name: "Zombie Shambler", system: "pf2e", challenge: "-1", environments: []string{"swamp", "underdark", "urban"}},
+}
+
+// DefaultBestiary returns the bestiary of built-in monsters.
+func DefaultBestiary() *Bestiary {
+	return &Bestiary{monsters: builtinMonsters}
+}
+
+// NewBestiary returns a bestiary of the built-in monsters, unless builtin is
+// false, and the monsters from the configuration file, which replace
+// built-in monsters with the same name. Monsters without a game system
+// belong to system.
+func NewBestiary(custom []config.Monster, builtin bool, system GameSystem) (*Bestiary, error) {
+	b := &Bestiary{}
+	if builtin {
+		b.monsters = slices.Clone(builtinMonsters)
+	}
+
+	for _, m := range custom {
+		name := strings.TrimSpace(m.Name)
+		if name == "" {
+			return nil, fmt.Errorf("monster without a name")
+		}
+		monsterSystem := system
+		if m.System != "" {
+			var err error
+			if monsterSystem, err = NewGameSystem(m.System); err != nil {
+				return nil, fmt.Errorf("monster %s: %w", name, err)
+			}
+		}
+		if _, err := monsterSystem.ParseChallenge(m.Challenge); err != nil {
+			return nil, fmt.Errorf("monster %s: %w", name, err)
+		}
+
+		if m.ArmorClass < 0 {
+			return nil, fmt.Errorf("monster %s: invalid armor class %d", name, m.ArmorClass)
+		}
+
+		monster := bestiaryMonster{
+			name:       name,
+			system:     monsterSystem.Name(),
+			challenge:  m.Challenge,
+			initiative: m.Initiative,
+			armorClass: m.ArmorClass,
+			statBlock:  strings.TrimSpace(m.StatBlock),
+		}
+		for _, environment := range m.Environments {
+			monster.environments = append(monster.environments, strings.ToLower(strings.TrimSpace(environment)))
+		}
+		b.monsters = slices.DeleteFunc(b.monsters, func(other bestiaryMonster) bool {
+			return other.system == monster.system && strings.EqualFold(other.name, monster.name)
+		})
+		b.monsters = append(b.monsters, monster)
+	}
+	return b, nil
+}
+
+// fillStats gives the monsters of the groups the initiative modifier, armor
+// class and stat block of the monster of the game system with the same name,
+// if there is one. A modifier entered with the monsters is kept.
+func (b *Bestiary) fillStats(system GameSystem, groups []monsterGroup) {
+	for g, group := range groups {
+		i := slices.IndexFunc(b.monsters, func(m bestiaryMonster) bool {
+			return m.system == system.Name() && strings.EqualFold(m.name, group.name)
+		})
+		if i < 0 {
+			continue
+		}
+		if group.initiative == (Initiative{}) {
+			groups[g].initiative = Initiative{Modifier: b.monsters[i].initiative}
+		}
+		for j, creature := range group.monsters {
+			if monster, ok := creature.(Monster); ok {
+				monster.initiative = groups[g].initiative
+				monster.armorClass, monster.statBlock = b.monsters[i].armorClass, b.monsters[i].statBlock
+				group.monsters[j] = monster
+			}
+		}
+	}
+}
+
+// environments returns where the monsters of a game system are found, in
+// alphabetical order.
+func (b *Bestiary) environments(system GameSystem) []string {
+	environments := []string{}
+	for _, m := range b.monsters {
+		if m.system != system.Name() {
+			continue
+		}
+		for _, environment := range m.environments {
+			if !slices.Contains(environments, environment) {
+				environments = append(environments, environment)
+			}
+		}
+	}
+	slices.Sort(environments)
+	return environments
+}
+
+// randomGroup is a kind of monster in a random encounter.
+type randomGroup struct {
+	name  string
+	count int
+}
+
+// maxRandomMonsters is the most monsters a random encounter has.
+const maxRandomMonsters = 12
+
+// randomEncounterAttempts is how many groups of monsters are tried before
+// giving up on a random encounter.
+const randomEncounterAttempts = 200
+
+// generate picks up to three kinds of monsters of a game system found in an
+// environment, or anywhere when it's empty, for an encounter of a
+// difficulty, an index of the system's difficulties, against a party of
+// characters with the given levels. The monsters cost at least the
+// difficulty's budget, and less than the next difficulty's.
+func (b *Bestiary) generate(system GameSystem, environment string, levels []int, difficulty int) ([]randomGroup, error) {
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("random encounters need the levels of the party, set in the Party tab")
+	}
+
+	difficulties := system.Difficulties()
+	lower := system.EncounterBudget(levels, difficulty)
+	upper := lower * 5 / 4
+	if difficulty+1 < len(difficulties) {
+		upper = system.EncounterBudget(levels, difficulty+1)
+	}
+
+	type candidate struct {
+		name      string
+		challenge float64
+	}
+	candidates := []candidate{}
+	for _, m := range b.monsters {
+		if m.system != system.Name() || (environment != "" && len(m.environments) > 0 && !slices.Contains(m.environments, environment)) {
+			continue
+		}
+		// the bestiary only holds challenges its systems can parse
+		challenge, _ := system.ParseChallenge(m.challenge)
+		if cost := system.EncounterCost(levels, []float64{challenge}); cost > 0 && cost < upper {
+			candidates = append(candidates, candidate{name: m.name, challenge: challenge})
+		}
+	}
+
+	for range randomEncounterAttempts {
+		if len(candidates) == 0 {
+			break
+		}
+
+		groups := []randomGroup{}
+		challenges := []float64{}
+		for range 1 + rand.IntN(3) {
+			c := candidates[rand.IntN(len(candidates))]
+			if slices.ContainsFunc(groups, func(g randomGroup) bool { return g.name == c.name }) {
+				continue
+			}
+
+			// add as many as fit, stopping at random once there are enough
+			count := 0
+			for len(challenges) < maxRandomMonsters {
+				next := append(challenges, c.challenge)
+				cost := system.EncounterCost(levels, next)
+				if cost >= upper {
+					break
+				}
+				challenges = next
+				count++
+				if cost >= lower && rand.IntN(2) == 0 {
+					break
+				}
+			}
+			if count > 0 {
+				groups = append(groups, randomGroup{name: c.name, count: count})
+			}
+		}
+
+		if cost := system.EncounterCost(levels, challenges); cost >= lower && cost < upper {
+			return groups, nil
+		}
+	}
+
+	monsters := "monsters"
+	if environment != "" {
+		monsters = environment + " monsters"
+	}
+	return nil, fmt.Errorf("no %s make a fitting %s encounter for the party", monsters, strings.ToLower(difficulties[difficulty]))
+}
+
+// formatRandomGroups lists the monsters of a random encounter the way they're
+// entered when creating an encounter, such as "Goblin x3, Ogre".
+func formatRandomGroups(groups []randomGroup) string {
+	entries := []string{}
+	for _, g := range groups {
+		if g.count > 1 {
+			entries = append(entries, fmt.Sprintf("%s x%d", g.name, g.count))
+			continue
+		}
+		entries = append(entries, g.name)
+	}
+	return strings.Join(entries, ", ")
+}
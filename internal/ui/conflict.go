@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"github.com/charmbracelet/huh"
+)
+
+// conflictResolution is how to save over a data file that was changed on
+// disk since it was loaded.
+type conflictResolution int
+
+const (
+	mergeChanges conflictResolution = iota
+	reloadChanges
+)
+
+// resolve resolves the conflict, merging the store with the data file or
+// reloading it.
+func (r conflictResolution) resolve(store *Store) error {
+	if r == reloadChanges {
+		return store.Reload()
+	}
+	return store.Merge()
+}
+
+// newConflictForm asks how to save over a data file that was changed on
+// disk. Aborting it leaves the changes unsaved.
+func newConflictForm(keyMap *huh.KeyMap, theme *Theme) *huh.Form {
+	return huh.NewForm(huh.NewGroup(
+		huh.NewSelect[conflictResolution]().
+			Key("resolution").
+			Title("The data file changed on disk").
+			Description("It was changed since it was loaded, such as by a script or another tracker").
+			Options(
+				huh.NewOption("Merge: keep the changes made here and on disk", mergeChanges),
+				huh.NewOption("Reload: discard the changes made here", reloadChanges),
+			),
+	)).
+		WithKeyMap(keyMap).
+		WithTheme(theme.formTheme())
+}
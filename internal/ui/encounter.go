@@ -1,9 +1,11 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"sort"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +16,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
 	"github.com/termkit/skeleton"
 )
 
@@ -25,6 +28,20 @@ const (
 	encounterPlaceholder encounterView = iota
 	encounterCreateForm
 	encounterDetail
+	encounterEventForm
+	encounterWaitForm
+	encounterRejoinForm
+	encounterRerollForm
+	encounterPopcornForm
+	encounterHealthForm
+	encounterConditionForm
+	encounterNotesForm
+	encounterNotesEditor
+	encounterConflictForm
+	encounterHitPointsForm
+	encounterRandomForm
+	encounterStatBlockForm
+	encounterStatBlockEditor
 )
 
 type encounter struct {
@@ -32,34 +49,97 @@ type encounter struct {
 
 	skeleton *skeleton.Skeleton
 	party    *map[string]Character
+	store    *Store
 
 	view                encounterView
 	encounterCreateForm *encounterCreationForm
+	form                *huh.Form
 	list                list.Model
 	help                help.Model
+	keys                KeyBindings
+	theme               *Theme
 	placeholderKeys     encounterPlaceholderKeyMap
 	detailKeys          encounterDetailKeyMap
+	palette             *palette
+
+	// eventKind is the kind of event being logged with the event form
+	eventKind CombatEventKind
+	// hitPointsFor is the ID of the creature whose hit points were clicked
+	hitPointsFor string
+	// notesFor is the ID of the creature whose notes are being edited, or
+	// empty for the encounter's notes
+	notesFor string
+	// statBlockFor is the ID of the creature whose armor class and stat
+	// block are being edited
+	statBlockFor string
+	// showStats shows the encounter's statistics instead of the initiative
+	// order
+	showStats bool
+
+	// turnLimit is the turn limit new encounters start with
+	turnLimit time.Duration
+	// system is the game system new encounters follow by default
+	system GameSystem
+	// bestiary holds the monsters random encounters are generated from
+	bestiary *Bestiary
+	// tick identifies the running timer tick, so that restarting the timer
+	// stops the previous one
+	tick int
+	// lastTick is when the timer last ticked
+	lastTick time.Time
+
+	// observer is notified whenever the running encounter changes
+	observer func(Encounter)
+	// interrupted is the encounter left running when the program last
+	// exited, which can be resumed from the placeholder
+	interrupted *Encounter
+	// conflictView is the view to return to once a conflict with changes
+	// made to the data file on disk is resolved
+	conflictView encounterView
+
+	// err is the error from archiving the last encounter, journaling the
+	// running one or saving a character's notes
+	err error
 }
 
-func newEncounter(skeleton *skeleton.Skeleton, party *map[string]Character) *encounter {
+func newEncounter(skeleton *skeleton.Skeleton, party *map[string]Character, o options) *encounter {
 	// Create empty list for initiative groups
-	initiativeList := list.New([]list.Item{}, &initiativeGroupItemDelegate{}, skeleton.GetContentWidth(), skeleton.GetContentHeight())
+	initiativeList := list.New([]list.Item{}, &initiativeGroupItemDelegate{theme: o.theme}, skeleton.GetContentWidth(), skeleton.GetContentHeight())
+	initiativeList.KeyMap = newListKeyMap()
 	initiativeList.SetStatusBarItemName("group", "groups")
 	initiativeList.SetShowTitle(false)
 	initiativeList.SetShowStatusBar(false)
 	initiativeList.SetShowHelp(false)
+	initiativeList.SetFilteringEnabled(false)
 	initiativeList.DisableQuitKeybindings()
+	o.theme.applyToList(&initiativeList)
 
-	return &encounter{
+	e := &encounter{
 		skeleton: skeleton,
 		party:    party,
+		store:    o.store,
 
 		view:            encounterPlaceholder,
 		list:            initiativeList,
-		help:            help.New(),
-		placeholderKeys: newEncounterPlaceholderKeyMap(),
-		detailKeys:      newEncounterDetailKeyMap(),
+		help:            o.theme.newHelp(),
+		keys:            o.keys,
+		theme:           o.theme,
+		placeholderKeys: newEncounterPlaceholderKeyMap(o.keys),
+		detailKeys:      newEncounterDetailKeyMap(o.keys),
+		palette:         newPalette(scriptPaletteCommands(), o.keys, o.theme),
+		turnLimit:       o.turnLimit,
+		system:          o.system,
+		bestiary:        o.bestiary,
+		observer:        o.notifyEncounter,
 	}
+
+	interrupted, ok, err := o.store.Interrupted()
+	if ok {
+		e.interrupted = &interrupted
+	}
+	e.err = err
+	e.placeholderKeys.resume.SetEnabled(ok)
+	return e
 }
 
 func (e encounter) Init() tea.Cmd {
@@ -68,6 +148,12 @@ func (e encounter) Init() tea.Cmd {
 
 func (e encounter) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case encounterTickMsg:
+		if msg.id != e.tick || e.view != encounterDetail {
+			return e, nil
+		}
+		e.lastTick = msg.t
+		return e, e.nextTick()
 	case tea.KeyMsg:
 		switch e.view {
 		case encounterPlaceholder:
@@ -76,173 +162,1591 @@ func (e encounter) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return startEncounterCreateMsg{}
 				})
 			}
+			if key.Matches(msg, e.placeholderKeys.random) {
+				return e, e.startRandomForm()
+			}
+			if key.Matches(msg, e.placeholderKeys.resume) && e.interrupted != nil {
+				return e, e.resume()
+			}
 		case encounterDetail:
-			if key.Matches(msg, e.detailKeys.back) {
-				e.view = encounterPlaceholder
-				e.IniativeGroups = []IniativeGroup{}
-				e.Summary = ""
-				e.StartedAt = time.Time{}
-				e.EndedAt = time.Time{}
-				e.encounterCreateForm = nil
+			if e.palette.active {
+				return e, e.updatePalette(msg)
+			}
+			switch {
+			case key.Matches(msg, e.detailKeys.palette):
+				return e, e.palette.open()
+			case key.Matches(msg, e.detailKeys.back):
+				return e, e.endEncounter()
+			case key.Matches(msg, e.detailKeys.nextTurn):
+				return e, e.nextTurn()
+			case key.Matches(msg, e.detailKeys.previousTurn):
+				e.previousTurn()
+				return e, nil
+			case key.Matches(msg, e.detailKeys.moveUp), key.Matches(msg, e.detailKeys.moveDown):
+				delta := 1
+				if key.Matches(msg, e.detailKeys.moveUp) {
+					delta = -1
+				}
+				i := e.MoveGroup(e.list.Index(), delta)
+				e.refreshList()
+				e.list.Select(i)
+				e.notify()
+				return e, nil
+			case key.Matches(msg, e.detailKeys.merge):
+				i := e.MergeGroup(e.list.Index())
+				e.refreshList()
+				e.list.Select(i)
+				e.notify()
+				return e, nil
+			case key.Matches(msg, e.detailKeys.split):
+				e.SplitGroup(e.list.Index())
+				e.refreshList()
+				e.notify()
+				return e, nil
+			case key.Matches(msg, e.detailKeys.damage):
+				return e, e.startEventForm(DamageEvent)
+			case key.Matches(msg, e.detailKeys.heal):
+				return e, e.startEventForm(HealingEvent)
+			case key.Matches(msg, e.detailKeys.hp):
+				return e, e.startHealthForm()
+			case key.Matches(msg, e.detailKeys.condition):
+				return e, e.startConditionForm()
+			case key.Matches(msg, e.detailKeys.notes):
+				return e, e.startNotesForm()
+			case key.Matches(msg, e.detailKeys.statBlock):
+				return e, e.startStatBlockForm()
+			case key.Matches(msg, e.detailKeys.wait):
+				return e, e.startWaitForm()
+			case key.Matches(msg, e.detailKeys.rejoin):
+				return e, e.startRejoinForm()
+			case key.Matches(msg, e.detailKeys.stats):
+				e.showStats = !e.showStats
 				return e, nil
 			}
 		}
+	case tea.MouseMsg:
+		if e.view == encounterDetail && !e.showStats {
+			return e, e.updateMouse(msg)
+		}
 	case startEncounterCreateMsg:
-		e.encounterCreateForm = newEncounterCreateForm(e.skeleton, e.party)
+		system := e.system
+		if msg.system != nil {
+			system = msg.system
+		}
+		e.encounterCreateForm = newEncounterCreateForm(e.skeleton, e.party, &e.store.version, e.keys, e.theme, e.turnLimit, system)
+		e.encounterCreateForm.summary = msg.summary
+		e.encounterCreateForm.monsterList = msg.monsters
+		e.encounterCreateForm.bestiary = e.bestiary
 		e.view = encounterCreateForm
 		return e, e.encounterCreateForm.Init()
 	case createEncounterMsg:
+		e.interrupted = nil
+		e.placeholderKeys.resume.SetEnabled(false)
+		e.err = nil
+		e.ID = uuid.New().String()
 		e.Summary = msg.summary
 		e.StartedAt = time.Now()
 		e.IniativeGroups = msg.initiativeGroups
+		e.Round = 1
+		e.Turn = 0
+		e.TurnStartedAt = e.StartedAt
+		e.TurnLimit = msg.turnLimit
+		e.TurnDurations = map[string][]time.Duration{}
+		e.Surprised = msg.surprised
+		e.SurpriseRules = msg.surpriseRules
+		e.Mode = msg.mode
+		e.Reroll = msg.reroll
+		e.System = msg.system
 		e.encounterCreateForm = nil
 
-		// Sort initiative groups by initiative value (highest to lowest)
-		sort.Slice(e.IniativeGroups, func(i, j int) bool {
-			return e.IniativeGroups[i].Iniative > e.IniativeGroups[j].Iniative
-		})
+		e.sortGroups()
+		e.skipSurprised()
+
+		e.refreshList()
+		e.list.Select(e.Turn)
+		e.view = encounterDetail
+
+		// Nobody has the turn until the DM picks who goes first
+		if e.Mode == PopcornInitiative {
+			e.Turn = -1
+			e.refreshList()
+			return e, e.passTurn()
+		}
+
+		e.notify()
+		return e, e.startTimer()
+	case cancelEncounterCreationMsg:
+		e.encounterCreateForm = nil
+		e.view = encounterPlaceholder
+		return e, nil
+	}
+
+	switch e.view {
+	case encounterCreateForm:
+		{
+			e.skeleton.LockTabs()
+
+			if e.encounterCreateForm != nil {
+				var cmd tea.Cmd
+				e.encounterCreateForm, cmd = e.encounterCreateForm.Update(msg)
+				return e, cmd
+			}
+		}
+	case encounterEventForm, encounterWaitForm, encounterRejoinForm, encounterRerollForm, encounterPopcornForm, encounterHealthForm, encounterConditionForm, encounterNotesForm, encounterNotesEditor, encounterHitPointsForm, encounterStatBlockForm, encounterStatBlockEditor:
+		{
+			form, cmd := e.form.Update(msg)
+			if f, ok := form.(*huh.Form); ok {
+				e.form = f
+			}
+
+			if e.form.State == huh.StateAborted {
+				e.form = nil
+				e.view = encounterDetail
+				return e, e.startTimer()
+			}
+
+			if e.form.State == huh.StateCompleted {
+				switch e.view {
+				case encounterEventForm:
+					// validation already ensures the amount is a positive number
+					amount, _ := strconv.Atoi(strings.TrimSpace(e.form.GetString("amount")))
+					e.Record(CombatEvent{
+						Kind:     e.eventKind,
+						Source:   e.form.GetString("source"),
+						Target:   e.form.GetString("target"),
+						Amount:   amount,
+						Critical: e.form.GetBool("critical"),
+						Killed:   e.form.GetBool("killed"),
+					})
+				case encounterHitPointsForm:
+					source := ""
+					if group, ok := e.ActiveGroup(); ok && len(group.Creatures) > 0 {
+						source = group.Creatures[0].ID()
+					}
+					// validation already ensures the amount is a positive number
+					amount, _ := strconv.Atoi(strings.TrimSpace(e.form.GetString("amount")))
+					e.Record(CombatEvent{
+						Kind:   e.form.Get("kind").(CombatEventKind),
+						Source: source,
+						Target: e.hitPointsFor,
+						Amount: amount,
+					})
+				case encounterWaitForm:
+					e.Wait(e.form.GetString("creature"), e.form.Get("action").(WaitAction))
+				case encounterRejoinForm:
+					e.Rejoin(e.form.GetString("creature"), e.form.GetInt("after"))
+				case encounterRerollForm:
+					initiatives := []int{}
+					for i, group := range e.IniativeGroups {
+						// validation already ensures the input can be parsed
+						initiative, _ := parseReroll(e.form.GetString(fmt.Sprintf("reroll_%d", i)), e.rollInitiative(group))
+						initiatives = append(initiatives, initiative)
+					}
+					e.SetInitiatives(initiatives)
+				case encounterPopcornForm:
+					e.PassTurn(e.form.GetInt("next"))
+				case encounterHealthForm:
+					// validation already ensures the hit points can be parsed
+					maxHP, _ := parseCount(e.form.GetString("maxHP"), 0)
+					hp, _ := parseCount(e.form.GetString("hp"), maxHP)
+					e.SetHealth(e.form.GetString("creature"), hp, maxHP)
+				case encounterConditionForm:
+					condition := e.form.Get("condition").(ConditionType)
+					// validation already ensures the value can be parsed
+					value, _ := parseCount(e.form.GetString("value"), 1)
+					e.ToggleCondition(e.form.GetString("creature"), condition, value)
+				case encounterNotesForm:
+					return e, e.startNotesEditor(e.form.GetString("creature"))
+				case encounterNotesEditor:
+					e.setNotes(e.notesFor, e.form.GetString("notes"))
+					if errors.Is(e.err, ErrDataChanged) {
+						e.refreshList()
+						e.view = encounterDetail
+						e.notify()
+						return e, e.startConflictForm()
+					}
+				case encounterStatBlockForm:
+					return e, e.startStatBlockEditor(e.form.GetString("creature"))
+				case encounterStatBlockEditor:
+					// validation already ensures the armor class can be parsed
+					armorClass, _ := parseCount(e.form.GetString("armorClass"), 0)
+					e.setStatBlock(e.statBlockFor, armorClass, strings.TrimSpace(e.form.GetString("statBlock")))
+					if errors.Is(e.err, ErrDataChanged) {
+						e.refreshList()
+						e.view = encounterDetail
+						e.notify()
+						return e, e.startConflictForm()
+					}
+				}
+				e.refreshList()
+				e.list.Select(max(e.Turn, 0))
+				e.form = nil
+				e.view = encounterDetail
+				e.notify()
+				return e, e.startTimer()
+			}
+
+			return e, cmd
+		}
+	case encounterRandomForm:
+		{
+			form, cmd := e.form.Update(msg)
+			if f, ok := form.(*huh.Form); ok {
+				e.form = f
+			}
+
+			if e.form.State == huh.StateAborted {
+				e.form = nil
+				e.view = encounterPlaceholder
+				return e, nil
+			}
+
+			if e.form.State == huh.StateCompleted {
+				system := e.form.Get("system").(GameSystem)
+				environment := e.form.GetString("environment")
+				difficulty := e.form.GetInt("difficulty")
+				e.form = nil
+				e.view = encounterPlaceholder
+
+				groups, err := e.bestiary.generate(system, environment, e.partyLevels(), difficulty)
+				e.err = err
+				if err != nil {
+					return e, nil
+				}
+				summary := system.Difficulties()[difficulty] + " encounter"
+				if environment != "" {
+					summary = system.Difficulties()[difficulty] + " " + environment + " encounter"
+				}
+				return e, func() tea.Msg {
+					return startEncounterCreateMsg{summary: summary, monsters: formatRandomGroups(groups), system: system}
+				}
+			}
+
+			return e, cmd
+		}
+	case encounterConflictForm:
+		{
+			form, cmd := e.form.Update(msg)
+			if f, ok := form.(*huh.Form); ok {
+				e.form = f
+			}
+
+			if e.form.State == huh.StateCompleted {
+				e.err = e.form.Get("resolution").(conflictResolution).resolve(e.store)
+			}
+			if e.form.State == huh.StateAborted || e.form.State == huh.StateCompleted {
+				e.form = nil
+				e.view = e.conflictView
+				if e.view == encounterDetail {
+					return e, e.startTimer()
+				}
+				return e, nil
+			}
+
+			return e, cmd
+		}
+	case encounterDetail:
+		{
+			var cmd tea.Cmd
+			e.list, cmd = e.list.Update(msg)
+
+			// Timer ticks are lost while another page is shown
+			if time.Since(e.lastTick) > 2*time.Second {
+				return e, tea.Batch(cmd, e.startTimer())
+			}
+			return e, cmd
+		}
+	}
+	return e, nil
+}
+
+func (e encounter) View() string {
+	switch e.view {
+	case encounterPlaceholder:
+		{
+			availHeight := e.skeleton.GetContentHeight()
+			e.help.Width = e.skeleton.GetContentWidth()
+			helpStyle := lipgloss.NewStyle().Padding(0, 1)
+			helpView := helpStyle.Render(e.help.View(e.placeholderKeys))
+			if e.err != nil {
+				helpView = lipgloss.JoinVertical(lipgloss.Left, e.theme.renderError(e.err), helpView)
+			}
+			availHeight = availHeight - lipgloss.Height(helpView)
+
+			// Create main content area
+			placeholderStyle := lipgloss.NewStyle().
+				Italic(true).
+				Foreground(e.theme.subtle).
+				Align(lipgloss.Center)
+			content := placeholderStyle.Render("No encounter started...")
+			if e.interrupted != nil {
+				content = lipgloss.JoinVertical(lipgloss.Center,
+					placeholderStyle.Render(fmt.Sprintf("%s was interrupted in round %d.", e.interrupted.Summary, e.interrupted.Round)),
+					placeholderStyle.Render("Resume it, or start a new encounter to discard it."),
+				)
+			}
+			contentArea := lipgloss.NewStyle().
+				Height(availHeight).
+				Width(e.skeleton.GetContentWidth()).
+				AlignHorizontal(lipgloss.Center).
+				AlignVertical(lipgloss.Center).
+				Render(content)
+
+			return lipgloss.JoinVertical(lipgloss.Left, contentArea, helpView)
+		}
+	case encounterCreateForm:
+		{
+			if e.encounterCreateForm != nil {
+				return e.encounterCreateForm.View()
+			}
+			return ""
+		}
+	case encounterEventForm, encounterWaitForm, encounterRejoinForm, encounterRerollForm, encounterPopcornForm, encounterHealthForm, encounterConditionForm, encounterNotesForm, encounterNotesEditor, encounterConflictForm, encounterHitPointsForm, encounterRandomForm, encounterStatBlockForm, encounterStatBlockEditor:
+		{
+			e.form.WithHeight(e.skeleton.GetContentHeight() - 2).WithWidth(e.skeleton.GetContentWidth() - 2)
+			return lipgloss.NewStyle().Padding(1).Render(e.form.View())
+		}
+	case encounterDetail:
+		{
+			layout := e.detailLayout()
+			if e.showStats {
+				return lipgloss.JoinVertical(lipgloss.Left, layout.header, statsView(e.Encounter, e.theme, layout.listHeight), layout.footer)
+			}
+
+			e.list.SetHeight(layout.listHeight)
+			e.list.SetWidth(layout.listWidth)
+			if layout.paneWidth > 0 {
+				order := lipgloss.NewStyle().Width(layout.listWidth).MaxWidth(layout.listWidth).Render(e.list.View())
+				pane := detailPaneView(e.Encounter, e.list.SelectedItem(), e.theme, layout.paneWidth, layout.listHeight)
+				return lipgloss.JoinVertical(lipgloss.Left, layout.header, lipgloss.JoinHorizontal(lipgloss.Top, order, pane), layout.footer)
+			}
+
+			return lipgloss.JoinVertical(lipgloss.Left, layout.header, e.list.View(), layout.footer)
+		}
+	}
+
+	return ""
+}
+
+// detailLayout is how the detail view is laid out: the header above the
+// initiative order, what's shown below it and the size left for the order.
+type detailLayout struct {
+	header     string
+	footer     string
+	listWidth  int
+	listHeight int
+	// paneWidth is the width of the pane beside the order, or zero when
+	// the terminal is too narrow for one
+	paneWidth int
+}
+
+func (e encounter) detailLayout() detailLayout {
+	helpStyle := lipgloss.NewStyle().Padding(0, 1)
+	availHeight := e.skeleton.GetContentHeight()
+	e.help.Width = e.skeleton.GetContentWidth()
+
+	// Create header with encounter summary
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(e.theme.title).
+		MarginBottom(1)
+	header := lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.UnsetMarginBottom().Render(fmt.Sprintf("Encounter: %s (Round %d)", e.Summary, e.Round)),
+		lipgloss.NewStyle().MarginBottom(1).Render(lipgloss.JoinVertical(lipgloss.Left, e.timersView(time.Now()), e.actionsView())),
+	)
+	help := helpStyle.Render(e.help.View(e.detailKeys))
+	if e.palette.active {
+		help = e.palette.View(e.skeleton.GetContentWidth())
+	}
+	if e.err != nil {
+		help = lipgloss.JoinVertical(lipgloss.Left, e.theme.renderError(e.err), help)
+	}
+
+	listHeight := availHeight - lipgloss.Height(header) - lipgloss.Height(help)
+	if waiting := waitingView(e.Encounter, e.theme); waiting != "" {
+		help = lipgloss.JoinVertical(lipgloss.Left, waiting, help)
+		listHeight -= lipgloss.Height(waiting)
+	}
+	// wide terminals show the selected group in a pane beside the list,
+	// which includes the notes
+	width := e.skeleton.GetContentWidth()
+	split := width >= splitPaneMinWidth
+	if notes := notesView(e.Encounter, e.list.SelectedItem(), e.theme, width, listHeight/3); notes != "" && !e.showStats && !split {
+		help = lipgloss.JoinVertical(lipgloss.Left, notes, help)
+		listHeight -= lipgloss.Height(notes)
+	}
+
+	layout := detailLayout{header: header, footer: help, listWidth: width, listHeight: listHeight}
+	if split {
+		layout.paneWidth = width * 2 / 5
+		layout.listWidth = width - layout.paneWidth
+	}
+	return layout
+}
+
+// timersView renders the encounter's duration and the current turn's
+// stopwatch, warning when the turn limit is exceeded.
+func (e encounter) timersView(now time.Time) string {
+	timerStyle := lipgloss.NewStyle().Foreground(e.theme.subtle)
+	encounterTimer := timerStyle.Render("Elapsed " + formatDuration(e.Duration(now)))
+
+	turnText := "Turn " + formatDuration(e.TurnElapsed(now))
+	if e.TurnLimit > 0 {
+		turnText += " / " + formatDuration(e.TurnLimit)
+	}
+	turnStyle := timerStyle
+	if e.TurnOverLimit(now) {
+		turnText += " — taking too long!"
+		turnStyle = lipgloss.NewStyle().Bold(true).Foreground(e.theme.error)
+	}
+
+	return encounterTimer + timerStyle.Render(" · ") + turnStyle.Render(turnText)
+}
+
+// actionsView renders what each creature whose turn it is can do under the
+// encounter's game system.
+func (e encounter) actionsView() string {
+	group, ok := e.ActiveGroup()
+	if !ok {
+		return ""
+	}
+
+	subtleStyle := lipgloss.NewStyle().Foreground(e.theme.subtle)
+	lines := []string{}
+	for _, creature := range group.Creatures {
+		actions := strings.Join(e.Rules().Actions(e.Conditions[creature.ID()]), " · ")
+		if actions == "" {
+			actions = "no actions"
+		}
+		lines = append(lines, subtleStyle.Render(fmt.Sprintf("%s: %s", creature.Name(), actions)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// endEncounter ends the encounter and archives it in the history.
+func (e *encounter) endEncounter() tea.Cmd {
+	e.End()
+	e.err = e.store.Archive(e.Encounter)
+
+	e.view = encounterPlaceholder
+	e.Encounter = Encounter{}
+	e.encounterCreateForm = nil
+	e.showStats = false
+	e.notify()
+	if errors.Is(e.err, ErrDataChanged) {
+		return e.startConflictForm()
+	}
+	return nil
+}
+
+// nextTurn advances to the next turn, re-rolling initiative at the end of
+// the round when the encounter asks for it.
+func (e *encounter) nextTurn() tea.Cmd {
+	if e.Mode == PopcornInitiative {
+		return e.passTurn()
+	}
+
+	round := e.Round
+	e.NextTurn()
+	if e.Round > round {
+		switch e.Reroll {
+		case RerollAuto:
+			e.SetInitiatives(e.RollInitiatives())
+		case RerollPrompt:
+			e.refreshList()
+			e.notify()
+			return e.startRerollForm()
+		}
+	}
+	e.refreshList()
+	e.list.Select(e.Turn)
+	e.notify()
+	return nil
+}
+
+// previousTurn goes back to the previous turn.
+func (e *encounter) previousTurn() {
+	e.PreviousTurn()
+	e.refreshList()
+	e.list.Select(e.Turn)
+	e.notify()
+}
+
+// updatePalette passes a key to the command palette, running the command
+// line once it's entered.
+func (e *encounter) updatePalette(msg tea.KeyMsg) tea.Cmd {
+	e.palette.complete = e.completions
+	line, cmd := e.palette.Update(msg)
+	if line == "" {
+		return cmd
+	}
+	return e.runCommand(line)
+}
+
+// runCommand runs a command line of the script language on the encounter.
+// Advancing the turn and ending the encounter work as their keys do, such
+// as asking for new initiatives at the end of the round.
+func (e *encounter) runCommand(line string) tea.Cmd {
+	args, err := splitCommand(line)
+	if err != nil {
+		e.palette.err = err
+		return nil
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "next":
+		e.palette.close()
+		return e.nextTurn()
+	case "prev":
+		e.palette.close()
+		e.previousTurn()
+		return nil
+	case "end":
+		e.palette.close()
+		return e.endEncounter()
+	}
+
+	party := map[string]Character{}
+	if e.party != nil {
+		party = *e.party
+	}
+	script := Script{Encounter: e.Encounter, party: party}
+	if err := script.Run(line); err != nil {
+		e.palette.err = err
+		return nil
+	}
+	e.palette.close()
+	e.Encounter = script.Encounter
+	e.refreshList()
+	e.notify()
+	return nil
+}
+
+// completions returns the names an argument of a command can be completed
+// with in the palette.
+func (e encounter) completions(arg commandArg) []string {
+	names := []string{}
+	switch arg {
+	case argCreature:
+		for _, creature := range e.Creatures() {
+			names = append(names, creature.Name())
+		}
+	case argCharacter:
+		if e.party != nil {
+			for _, character := range sortedCharacters(*e.party) {
+				names = append(names, character.Name())
+			}
+		}
+	case argCondition:
+		for _, condition := range e.Rules().Conditions() {
+			names = append(names, condition.Name)
+		}
+	}
+	return names
+}
+
+// updateMouse selects the group clicked in the initiative order, or moves
+// through the order with the mouse wheel. Clicking a creature's hit points,
+// in the order or in the pane beside it, asks for damage or healing to log.
+func (e *encounter) updateMouse(msg tea.MouseMsg) tea.Cmd {
+	if scrollList(&e.list, msg) || !isClick(msg) {
+		return nil
+	}
+
+	// lay the order out as it's drawn, which decides the groups on its page
+	layout := e.detailLayout()
+	e.list.SetHeight(layout.listHeight)
+	e.list.SetWidth(layout.listWidth)
+
+	y := msg.Y - lipgloss.Height(layout.header)
+	if layout.paneWidth > 0 && msg.X >= layout.listWidth {
+		if id := paneHitPointsAt(e.Encounter, e.list.SelectedItem(), e.theme, layout.paneWidth, y); id != "" {
+			return e.startHitPointsForm(id)
+		}
+		return nil
+	}
+
+	index, line, ok := listItemAt(e.list, &initiativeGroupItemDelegate{theme: e.theme}, y)
+	if !ok {
+		return nil
+	}
+	selected := index == e.list.Index()
+	e.list.Select(index)
+	if item, ok := e.list.SelectedItem().(initiativeGroupItem); ok && line == 1 {
+		if id := item.hitPointsAt(msg.X, selected); id != "" {
+			return e.startHitPointsForm(id)
+		}
+	}
+	return nil
+}
+
+// startEventForm shows a form to log damage or healing, done by the creature
+// whose turn it is to the selected group by default.
+func (e *encounter) startEventForm(kind CombatEventKind) tea.Cmd {
+	creatures := []huh.Option[string]{}
+	for _, creature := range e.Creatures() {
+		creatures = append(creatures, huh.NewOption(creature.Name(), creature.ID()))
+	}
+	if len(creatures) == 0 {
+		return nil
+	}
+
+	source := ""
+	if group, ok := e.ActiveGroup(); ok && len(group.Creatures) > 0 {
+		source = group.Creatures[0].ID()
+	}
+	target := ""
+	if item, ok := e.list.SelectedItem().(initiativeGroupItem); ok && len(item.group.Creatures) > 0 {
+		target = item.group.Creatures[0].ID()
+	}
+
+	title, sourceTitle := "Damage", "Dealt by"
+	if kind == HealingEvent {
+		title, sourceTitle = "Healing", "Healed by"
+	}
+
+	fields := []huh.Field{
+		huh.NewNote().Title(title),
+		huh.NewSelect[string]().
+			Key("source").
+			Title(sourceTitle).
+			Options(append([]huh.Option[string]{huh.NewOption("Something else", "")}, creatures...)...).
+			Value(&source),
+		huh.NewSelect[string]().
+			Key("target").
+			Title("Target").
+			Options(creatures...).
+			Value(&target),
+		huh.NewInput().
+			Key("amount").
+			Title("Amount").
+			Validate(validateAmount).
+			Inline(true),
+	}
+	if kind == DamageEvent {
+		fields = append(fields,
+			huh.NewConfirm().Key("critical").Title("Critical hit?").Affirmative("Yes").Negative("No"),
+			huh.NewConfirm().Key("killed").Title("Killed the target?").Affirmative("Yes").Negative("No"),
+		)
+	}
+
+	e.eventKind = kind
+	e.form = huh.NewForm(huh.NewGroup(fields...)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme()).
+		WithShowErrors(true)
+	e.view = encounterEventForm
+	return e.form.Init()
+}
+
+// startRandomForm shows a form to generate a random encounter for the
+// party, which is then reviewed in the encounter creation form.
+func (e *encounter) startRandomForm() tea.Cmd {
+	system := e.system
+	environment := ""
+	difficulty := 1
+
+	systems := []huh.Option[GameSystem]{}
+	for _, s := range gameSystems {
+		systems = append(systems, huh.NewOption(s.Title(), s))
+	}
+	environments := func() []huh.Option[string] {
+		options := []huh.Option[string]{huh.NewOption("Anywhere", "")}
+		for _, environment := range e.bestiary.environments(system) {
+			options = append(options, huh.NewOption(strings.ToUpper(environment[:1])+environment[1:], environment))
+		}
+		return options
+	}
+	difficulties := func() []huh.Option[int] {
+		options := []huh.Option[int]{}
+		for i, name := range system.Difficulties() {
+			options = append(options, huh.NewOption(name, i))
+		}
+		return options
+	}
+
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewNote().
+			Title("Random encounter").
+			Description("Against the party's levels, to review before it starts"),
+		huh.NewSelect[GameSystem]().
+			Key("system").
+			Title("Game system").
+			Options(systems...).
+			Value(&system),
+		huh.NewSelect[string]().
+			Key("environment").
+			Title("Environment").
+			OptionsFunc(environments, &system).
+			Height(8).
+			Value(&environment),
+		huh.NewSelect[int]().
+			Key("difficulty").
+			Title("Difficulty").
+			OptionsFunc(difficulties, &system).
+			Height(7).
+			Value(&difficulty),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme())
+	e.view = encounterRandomForm
+	return e.form.Init()
+}
+
+// partyLevels returns the levels of the party's characters whose level is
+// known.
+func (e encounter) partyLevels() []int {
+	levels := []int{}
+	if e.party == nil {
+		return levels
+	}
+	for _, character := range sortedCharacters(*e.party) {
+		if character.Level() > 0 {
+			levels = append(levels, character.Level())
+		}
+	}
+	return levels
+}
+
+// startHitPointsForm shows a form to log damage or healing to a creature
+// whose hit points were clicked, done by the creature whose turn it is.
+func (e *encounter) startHitPointsForm(id string) tea.Cmd {
+	name := ""
+	for _, creature := range e.Creatures() {
+		if creature.ID() == id {
+			name = creature.Name()
+		}
+	}
+
+	kind := DamageEvent
+	e.hitPointsFor = id
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewNote().
+			Title(name).
+			Description(e.Health[id].describe(e.Rules())),
+		huh.NewSelect[CombatEventKind]().
+			Key("kind").
+			Options(huh.NewOption("Damage", DamageEvent), huh.NewOption("Healing", HealingEvent)).
+			Value(&kind),
+		huh.NewInput().
+			Key("amount").
+			Title("Amount").
+			Validate(validateAmount).
+			Inline(true),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme()).
+		WithShowErrors(true)
+	e.view = encounterHitPointsForm
+	return e.form.Init()
+}
+
+// validateAmount validates the amount of damage or healing.
+func validateAmount(str string) error {
+	value, err := strconv.Atoi(strings.TrimSpace(str))
+	if err != nil || value <= 0 {
+		return fmt.Errorf("Amount must be a positive number")
+	}
+	return nil
+}
+
+// startWaitForm shows a form to take a creature out of the initiative order
+// to delay or ready an action, the first creature of the selected group by
+// default.
+func (e *encounter) startWaitForm() tea.Cmd {
+	creatures := []huh.Option[string]{}
+	for _, group := range e.IniativeGroups {
+		for _, creature := range group.Creatures {
+			creatures = append(creatures, huh.NewOption(creature.Name(), creature.ID()))
+		}
+	}
+	if len(creatures) == 0 {
+		return nil
+	}
+
+	creature := ""
+	if item, ok := e.list.SelectedItem().(initiativeGroupItem); ok && len(item.group.Creatures) > 0 {
+		creature = item.group.Creatures[0].ID()
+	}
+
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewNote().Title("Delay or ready"),
+		huh.NewSelect[string]().
+			Key("creature").
+			Title("Creature").
+			Options(creatures...).
+			Value(&creature),
+		huh.NewSelect[WaitAction]().
+			Key("action").
+			Title("Action").
+			Options(
+				huh.NewOption("Delay", Delay),
+				huh.NewOption("Ready an action", Ready),
+			),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme())
+	e.view = encounterWaitForm
+	return e.form.Init()
+}
+
+// startHealthForm shows a form to set the hit points of a creature, the
+// first creature of the selected group by default. Clearing the maximum
+// stops tracking them.
+func (e *encounter) startHealthForm() tea.Cmd {
+	creatures := []huh.Option[string]{}
+	for _, creature := range e.Creatures() {
+		creatures = append(creatures, huh.NewOption(creature.Name(), creature.ID()))
+	}
+	if len(creatures) == 0 {
+		return nil
+	}
+
+	creature := ""
+	if item, ok := e.list.SelectedItem().(initiativeGroupItem); ok && len(item.group.Creatures) > 0 {
+		creature = item.group.Creatures[0].ID()
+	}
+	maxHP, hp := "", ""
+	if h, ok := e.Health[creature]; ok {
+		maxHP, hp = strconv.Itoa(h.MaxHP), strconv.Itoa(h.HP)
+	}
+
+	validate := func(str string) error {
+		if _, err := parseCount(str, 0); err != nil {
+			return fmt.Errorf("Hit points must be a number")
+		}
+		return nil
+	}
+
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewNote().Title("Hit points"),
+		huh.NewSelect[string]().
+			Key("creature").
+			Title("Creature").
+			Options(creatures...).
+			Value(&creature),
+		huh.NewInput().
+			Key("maxHP").
+			Title("Maximum").
+			Description("Leave blank to stop tracking").
+			Value(&maxHP).
+			Validate(validate),
+		huh.NewInput().
+			Key("hp").
+			Title("Current").
+			Description("Leave blank for the maximum").
+			Value(&hp).
+			Validate(validate),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme()).
+		WithShowErrors(true)
+	e.view = encounterHealthForm
+	return e.form.Init()
+}
+
+// startConditionForm shows a form to give a creature one of the game
+// system's conditions or take it away, the first creature of the selected
+// group by default.
+func (e *encounter) startConditionForm() tea.Cmd {
+	creatures := []huh.Option[string]{}
+	for _, creature := range e.Creatures() {
+		creatures = append(creatures, huh.NewOption(creature.Name(), creature.ID()))
+	}
+	if len(creatures) == 0 {
+		return nil
+	}
+
+	creature := ""
+	if item, ok := e.list.SelectedItem().(initiativeGroupItem); ok && len(item.group.Creatures) > 0 {
+		creature = item.group.Creatures[0].ID()
+	}
+
+	conditions := []huh.Option[ConditionType]{}
+	for _, condition := range e.Rules().Conditions() {
+		name := condition.Name
+		if condition.Valued {
+			name += " (valued)"
+		}
+		conditions = append(conditions, huh.NewOption(name, condition))
+	}
+
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewNote().
+			Title("Conditions").
+			Description("Picking a condition the creature has takes it away"),
+		huh.NewSelect[string]().
+			Key("creature").
+			Title("Creature").
+			Options(creatures...).
+			Value(&creature),
+		huh.NewSelect[ConditionType]().
+			Key("condition").
+			Title("Condition").
+			Options(conditions...),
+		huh.NewInput().
+			Key("value").
+			Title("Value").
+			Description("For valued conditions such as Frightened 2, 0 takes it away").
+			Placeholder("1").
+			Validate(func(str string) error {
+				if _, err := parseCount(str, 1); err != nil {
+					return fmt.Errorf("Value must be a number")
+				}
+				return nil
+			}),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme()).
+		WithShowErrors(true)
+	e.view = encounterConditionForm
+	return e.form.Init()
+}
+
+// startNotesForm asks whose notes to edit: the encounter's, or a creature's,
+// the first creature of the selected group by default.
+func (e *encounter) startNotesForm() tea.Cmd {
+	options := []huh.Option[string]{huh.NewOption("The encounter", "")}
+	for _, creature := range e.Creatures() {
+		options = append(options, huh.NewOption(creature.Name(), creature.ID()))
+	}
+
+	creature := ""
+	if item, ok := e.list.SelectedItem().(initiativeGroupItem); ok && len(item.group.Creatures) > 0 {
+		creature = item.group.Creatures[0].ID()
+	}
+
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewSelect[string]().
+			Key("creature").
+			Title("Notes on").
+			Options(options...).
+			Value(&creature),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme())
+	e.view = encounterNotesForm
+	return e.form.Init()
+}
+
+// startNotesEditor shows a text area to edit the notes of the creature with
+// the given ID, or of the encounter when it's empty.
+func (e *encounter) startNotesEditor(id string) tea.Cmd {
+	title, notes := "Notes on "+e.Summary, e.Notes
+	if i := slices.IndexFunc(e.Creatures(), func(c Creature) bool { return c.ID() == id }); i >= 0 {
+		creature := e.Creatures()[i]
+		title, notes = "Notes on "+creature.Name(), creature.Notes()
+	}
+
+	e.notesFor = id
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewText().
+			Key("notes").
+			Title(title).
+			Description("Markdown: # headings, - lists, **bold**, *italics*, `code`").
+			Lines(12).
+			Value(&notes),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme())
+	e.view = encounterNotesEditor
+	return e.form.Init()
+}
+
+// setNotes replaces the notes of the creature with the given ID, or of the
+// encounter when it's empty. A character's notes are saved with the party.
+func (e *encounter) setNotes(id, notes string) {
+	if id == "" {
+		e.Notes = notes
+		return
+	}
+	e.SetCreatureNotes(id, notes)
+
+	if character, ok := (*e.party)[id]; ok {
+		character.notes = notes
+		(*e.party)[id] = character
+		e.err = e.store.Save()
+	}
+}
+
+// startStatBlockForm asks whose armor class and stat block to edit, the
+// first creature of the selected group by default.
+func (e *encounter) startStatBlockForm() tea.Cmd {
+	creatures := []huh.Option[string]{}
+	for _, creature := range e.Creatures() {
+		creatures = append(creatures, huh.NewOption(creature.Name(), creature.ID()))
+	}
+	if len(creatures) == 0 {
+		return nil
+	}
 
-		// Update the list with sorted initiative groups
-		items := []list.Item{}
-		for _, group := range e.IniativeGroups {
-			items = append(items, initiativeGroupItem{group: group})
+	creature := ""
+	if item, ok := e.list.SelectedItem().(initiativeGroupItem); ok && len(item.group.Creatures) > 0 {
+		creature = item.group.Creatures[0].ID()
+	}
+
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewSelect[string]().
+			Key("creature").
+			Title("Stat block of").
+			Options(creatures...).
+			Value(&creature),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme())
+	e.view = encounterStatBlockForm
+	return e.form.Init()
+}
+
+// startStatBlockEditor shows a form to edit the armor class and stat block
+// of the creature with the given ID.
+func (e *encounter) startStatBlockEditor(id string) tea.Cmd {
+	i := slices.IndexFunc(e.Creatures(), func(c Creature) bool { return c.ID() == id })
+	if i < 0 {
+		e.view = encounterDetail
+		return e.startTimer()
+	}
+	creature := e.Creatures()[i]
+	armorClass, statBlock := "", creature.StatBlock()
+	if creature.ArmorClass() > 0 {
+		armorClass = strconv.Itoa(creature.ArmorClass())
+	}
+
+	e.statBlockFor = id
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewInput().
+			Key("armorClass").
+			Title("Armor class of "+creature.Name()).
+			Description("Leave blank if it isn't known").
+			Value(&armorClass).
+			Validate(func(str string) error {
+				if _, err := parseCount(str, 0); err != nil {
+					return fmt.Errorf("Armor class must be a number")
+				}
+				return nil
+			}),
+		huh.NewText().
+			Key("statBlock").
+			Title("Stat block").
+			Description("Markdown: # headings, - lists, **bold**, *italics*, `code`").
+			Lines(12).
+			Value(&statBlock),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme()).
+		WithShowErrors(true)
+	e.view = encounterStatBlockEditor
+	return e.form.Init()
+}
+
+// setStatBlock replaces the armor class and stat block of the creature with
+// the given ID. A character's are saved with the party.
+func (e *encounter) setStatBlock(id string, armorClass int, statBlock string) {
+	e.SetCreatureStats(id, armorClass, statBlock)
+
+	if character, ok := (*e.party)[id]; ok {
+		character.armorClass, character.statBlock = armorClass, statBlock
+		(*e.party)[id] = character
+		e.err = e.store.Save()
+	}
+}
+
+// startConflictForm asks how to save over a data file that was changed on
+// disk, returning to the current view once answered.
+func (e *encounter) startConflictForm() tea.Cmd {
+	e.conflictView = e.view
+	e.form = newConflictForm(customFormKeyMap(e.keys), e.theme)
+	e.view = encounterConflictForm
+	return e.form.Init()
+}
+
+// notesView renders the encounter's notes and the notes of the creatures in
+// the selected initiative group, or nothing when there are none, cut to
+// height.
+func notesView(e Encounter, selected list.Item, theme *Theme, width, height int) string {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.initiative)
+
+	sections := []string{}
+	if strings.TrimSpace(e.Notes) != "" {
+		sections = append(sections, titleStyle.Render("Notes"), renderMarkdown(e.Notes, theme, width-4))
+	}
+	if item, ok := selected.(initiativeGroupItem); ok {
+		for _, creature := range item.group.Creatures {
+			if strings.TrimSpace(creature.Notes()) != "" {
+				sections = append(sections, titleStyle.Render(creature.Name()), renderMarkdown(creature.Notes(), theme, width-4))
+			}
+		}
+	}
+	if len(sections) == 0 || height <= 1 {
+		return ""
+	}
+
+	content := strings.Join(sections, "\n")
+	if lines := strings.Split(content, "\n"); len(lines) > height-1 {
+		content = strings.Join(lines[:height-1], "\n")
+	}
+	return lipgloss.NewStyle().Padding(0, 2, 1).Render(content)
+}
+
+// splitPaneMinWidth is the narrowest the encounter detail view can be to
+// show the selected group in a pane beside the initiative order.
+const splitPaneMinWidth = 100
+
+// detailPaneView renders the creatures of the selected group for the pane
+// beside the initiative order: their hit points, armor class, conditions,
+// what they can do, their statistics so far, stat block and notes, followed
+// by the encounter's notes.
+func detailPaneView(e Encounter, selected list.Item, theme *Theme, width, height int) string {
+	sections := []string{}
+	for _, section := range detailPaneSections(e, selected, theme, width) {
+		sections = append(sections, section.content)
+	}
+
+	content := strings.Join(sections, "\n\n")
+	if lines := strings.Split(content, "\n"); len(lines) > height {
+		content = strings.Join(lines[:max(height, 0)], "\n")
+	}
+	// the border is drawn outside the width
+	return lipgloss.NewStyle().
+		Width(width-1).
+		Height(height).
+		MaxHeight(height).
+		Padding(0, 1).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(theme.subtle).
+		Render(content)
+}
+
+// paneSection is a section of the pane beside the initiative order, about a
+// creature of the selected group or, with no creature, the encounter's
+// notes.
+type paneSection struct {
+	creature string
+	content  string
+}
+
+// detailPaneSections returns the sections of the pane beside the initiative
+// order, which are separated by a blank line.
+func detailPaneSections(e Encounter, selected list.Item, theme *Theme, width int) []paneSection {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.initiative)
+	subtleStyle := lipgloss.NewStyle().Foreground(theme.subtle)
+	textStyle := lipgloss.NewStyle().Foreground(theme.text)
+	contentWidth := max(width-3, 1)
+
+	sections := []paneSection{}
+	if item, ok := selected.(initiativeGroupItem); ok {
+		stats := e.Stats()
+		for _, creature := range item.group.Creatures {
+			lines := []string{titleStyle.Render(creature.Name())}
+
+			if h, ok := e.Health[creature.ID()]; ok {
+				lines = append(lines, textStyle.Render(h.describe(e.Rules())))
+			} else {
+				lines = append(lines, subtleStyle.Render("Hit points not tracked"))
+			}
+			if creature.ArmorClass() > 0 {
+				lines = append(lines, textStyle.Render(fmt.Sprintf("AC %d", creature.ArmorClass())))
+			}
+			conditions := []string{}
+			for _, condition := range e.Conditions[creature.ID()] {
+				conditions = append(conditions, condition.String())
+			}
+			if e.IsSurprised(creature.ID()) {
+				conditions = append(conditions, "Surprised")
+			}
+			if len(conditions) > 0 {
+				lines = append(lines, textStyle.Render(strings.Join(conditions, ", ")))
+			}
+			if actions := e.Rules().Actions(e.Conditions[creature.ID()]); len(actions) > 0 {
+				lines = append(lines, subtleStyle.Render(strings.Join(actions, " · ")))
+			} else {
+				lines = append(lines, subtleStyle.Render("Can't act"))
+			}
+
+			s := stats[creature.ID()]
+			summary := fmt.Sprintf("Dealt %d · Taken %d · Healed %d", s.DamageDealt, s.DamageTaken, s.HealingDone)
+			if s.Turns > 0 {
+				summary += " · Avg turn " + formatDuration(s.AverageTurn())
+			}
+			lines = append(lines, subtleStyle.Render(summary))
+
+			if strings.TrimSpace(creature.StatBlock()) != "" {
+				lines = append(lines, "", renderMarkdown(creature.StatBlock(), theme, contentWidth))
+			}
+			if strings.TrimSpace(creature.Notes()) != "" {
+				lines = append(lines, "", renderMarkdown(creature.Notes(), theme, contentWidth))
+			}
+			sections = append(sections, paneSection{creature: creature.ID(), content: strings.Join(lines, "\n")})
+		}
+	}
+	if strings.TrimSpace(e.Notes) != "" {
+		sections = append(sections, paneSection{content: titleStyle.Render("Notes") + "\n" + renderMarkdown(e.Notes, theme, contentWidth)})
+	}
+	return sections
+}
+
+// paneHitPointsAt returns the ID of the creature whose tracked hit points
+// are drawn at row y of the pane beside the initiative order, or empty if
+// none are.
+func paneHitPointsAt(e Encounter, selected list.Item, theme *Theme, width, y int) string {
+	row := 0
+	for _, section := range detailPaneSections(e, selected, theme, width) {
+		// hit points are drawn below the creature's name
+		if _, ok := e.Health[section.creature]; ok && y == row+1 {
+			return section.creature
+		}
+		row += lipgloss.Height(section.content) + 1
+	}
+	return ""
+}
+
+// startRejoinForm shows a form to put a waiting creature back into the
+// initiative order, after the group whose turn it is by default.
+func (e *encounter) startRejoinForm() tea.Cmd {
+	if len(e.Waiting) == 0 {
+		return nil
+	}
+
+	creatures := []huh.Option[string]{}
+	for _, waiting := range e.Waiting {
+		creatures = append(creatures, huh.NewOption(waiting.Creature.Name(), waiting.Creature.ID()))
+	}
+
+	positions := []huh.Option[int]{huh.NewOption("At the top", -1)}
+	for i, group := range e.IniativeGroups {
+		names := []string{}
+		for _, creature := range group.Creatures {
+			names = append(names, creature.Name())
+		}
+		positions = append(positions, huh.NewOption(fmt.Sprintf("After %s (%d)", strings.Join(names, ", "), group.Iniative), i))
+	}
+	after := e.Turn
+
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewNote().Title("Rejoin"),
+		huh.NewSelect[string]().
+			Key("creature").
+			Title("Creature").
+			Options(creatures...),
+		huh.NewSelect[int]().
+			Key("after").
+			Title("Position").
+			Options(positions...).
+			Value(&after),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme())
+	e.view = encounterRejoinForm
+	return e.form.Init()
+}
+
+// startRerollForm asks for each group's initiative for the new round, or the
+// speed modifier to roll it with. Aborting the form keeps the order.
+func (e *encounter) startRerollForm() tea.Cmd {
+	if len(e.IniativeGroups) == 0 {
+		return nil
+	}
+
+	fields := []huh.Field{
+		huh.NewNote().
+			Title(fmt.Sprintf("Initiative for round %d", e.Round)).
+			Description("Leave blank to roll, enter a speed modifier such as +2 or -5 to roll with it, or enter the initiative"),
+	}
+	for i, group := range e.IniativeGroups {
+		names := []string{}
+		for _, creature := range group.Creatures {
+			names = append(names, creature.Name())
+		}
+		title := strings.Join(names, ", ")
+		if group.Side != "" {
+			title = group.Side
 		}
-		e.list.SetItems(items)
-		e.view = encounterDetail
-		return e, nil
-	case cancelEncounterCreationMsg:
-		e.encounterCreateForm = nil
-		e.view = encounterPlaceholder
-		return e, nil
+
+		roll := e.rollInitiative(group)
+		fields = append(fields, huh.NewInput().
+			Key(fmt.Sprintf("reroll_%d", i)).
+			Title(title).
+			Description(fmt.Sprintf("%s %s", e.Rules().InitiativeName(), group.Modifiers())).
+			Validate(func(str string) error {
+				if _, err := parseReroll(str, roll); err != nil {
+					return fmt.Errorf("Must be blank, a speed modifier like +2 or an initiative")
+				}
+				return nil
+			}))
 	}
 
-	switch e.view {
-	case encounterCreateForm:
-		{
-			e.skeleton.LockTabs()
+	e.form = huh.NewForm(huh.NewGroup(fields...)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme())
+	e.view = encounterRerollForm
+	return e.form.Init()
+}
 
-			if e.encounterCreateForm != nil {
-				var cmd tea.Cmd
-				e.encounterCreateForm, cmd = e.encounterCreateForm.Update(msg)
-				return e, cmd
-			}
+// passTurn ends the turn in popcorn initiative, asking who goes next when
+// there's a choice. Aborting the form keeps the turn where it is.
+func (e *encounter) passTurn() tea.Cmd {
+	candidates := e.NextCandidates()
+	if len(candidates) <= 1 {
+		e.NextTurn()
+		e.refreshList()
+		e.list.Select(max(e.Turn, 0))
+		e.notify()
+		return e.startTimer()
+	}
+
+	groupNames := func(group IniativeGroup) string {
+		names := []string{}
+		for _, creature := range group.Creatures {
+			names = append(names, creature.Name())
 		}
-	case encounterDetail:
-		{
-			var cmd tea.Cmd
-			e.list, cmd = e.list.Update(msg)
-			return e, cmd
+		return strings.Join(names, ", ")
+	}
+
+	title := "Who goes first?"
+	if group, ok := e.ActiveGroup(); ok {
+		title = fmt.Sprintf("%s picks who goes next", groupNames(group))
+		// The active group is only a candidate once everyone has acted
+		if slices.Contains(candidates, e.Turn) {
+			title = fmt.Sprintf("%s picks who starts round %d", groupNames(group), e.Round+1)
 		}
 	}
-	return e, nil
+
+	options := []huh.Option[int]{}
+	for _, i := range candidates {
+		options = append(options, huh.NewOption(groupNames(e.IniativeGroups[i]), i))
+	}
+
+	e.form = huh.NewForm(huh.NewGroup(
+		huh.NewSelect[int]().
+			Key("next").
+			Title(title).
+			Options(options...),
+	)).
+		WithKeyMap(customFormKeyMap(e.keys)).
+		WithTheme(e.theme.formTheme())
+	e.view = encounterPopcornForm
+	return e.form.Init()
 }
 
-func (e encounter) View() string {
-	switch e.view {
-	case encounterPlaceholder:
-		{
-			availHeight := e.skeleton.GetContentHeight()
-			e.help.Width = e.skeleton.GetContentWidth()
-			helpStyle := lipgloss.NewStyle().Padding(0, 1)
-			helpView := helpStyle.Render(e.help.View(e.placeholderKeys))
-			availHeight = availHeight - lipgloss.Height(helpView)
+// parseReroll parses a new initiative entered in the reroll form. Nothing
+// rolls it, a signed number rolls it with that speed modifier, and any other
+// number is the initiative itself.
+func parseReroll(str string, roll func() int) (int, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return roll(), nil
+	}
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, err
+	}
+	if strings.HasPrefix(str, "+") || strings.HasPrefix(str, "-") {
+		return roll() + value, nil
+	}
+	return value, nil
+}
 
-			// Create main content area
-			placeholderStyle := lipgloss.NewStyle().
-				Italic(true).
-				Foreground(lipgloss.Color("240")).
-				Align(lipgloss.Center)
-			content := placeholderStyle.Render("No encounter started...")
-			contentArea := lipgloss.NewStyle().
-				Height(availHeight).
-				Width(e.skeleton.GetContentWidth()).
-				AlignHorizontal(lipgloss.Center).
-				AlignVertical(lipgloss.Center).
-				Render(content)
+// rollInitiative returns a function rolling a new initiative for the group
+// by the rules of the encounter's game system.
+func (e encounter) rollInitiative(group IniativeGroup) func() int {
+	rules := e.Rules()
+	return func() int {
+		return rules.RollInitiative(group.Modifiers(), false)
+	}
+}
 
-			return lipgloss.JoinVertical(lipgloss.Left, contentArea, helpView)
-		}
-	case encounterCreateForm:
-		{
-			if e.encounterCreateForm != nil {
-				return e.encounterCreateForm.View()
-			}
-			return ""
-		}
-	case encounterDetail:
-		{
-			helpStyle := lipgloss.NewStyle().Padding(0, 1)
-			availHeight := e.skeleton.GetContentHeight()
-			e.help.Width = e.skeleton.GetContentWidth()
+// parseCount parses a whole number of zero or more entered in a form, such
+// as hit points, where nothing means blank.
+func parseCount(str string, blank int) (int, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return blank, nil
+	}
+	value, err := strconv.Atoi(str)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid number %q", str)
+	}
+	return value, nil
+}
 
-			// Create header with encounter summary
-			headerStyle := lipgloss.NewStyle().
-				Bold(true).
-				Foreground(lipgloss.Color("205")).
-				MarginBottom(1)
-			header := headerStyle.Render(fmt.Sprintf("Encounter: %s", e.Summary))
-			help := helpStyle.Render(e.help.View(e.detailKeys))
+// waitingView renders the creatures waiting to rejoin the initiative order,
+// or nothing when there are none.
+func waitingView(e Encounter, theme *Theme) string {
+	if len(e.Waiting) == 0 {
+		return ""
+	}
 
-			listHeight := availHeight - lipgloss.Height(header) - lipgloss.Height(help)
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.initiative)
+	creatureStyle := lipgloss.NewStyle().Foreground(theme.text)
+	subtleStyle := lipgloss.NewStyle().Foreground(theme.subtle)
 
-			e.list.SetHeight(listHeight)
-			e.list.SetWidth(e.skeleton.GetContentWidth())
+	lines := []string{titleStyle.Render("Waiting")}
+	for _, waiting := range e.Waiting {
+		lines = append(lines, "  "+creatureStyle.Render(waiting.Creature.Name())+
+			subtleStyle.Render(fmt.Sprintf(" %s (was %d)", waiting.Action, waiting.Initiative)))
+	}
+	return lipgloss.NewStyle().Padding(0, 2, 1).Render(strings.Join(lines, "\n"))
+}
 
-			return lipgloss.JoinVertical(lipgloss.Left, header, e.list.View(), help)
-		}
+// formatDuration formats d as minutes and seconds, with hours when needed.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d / time.Hour)
+	m := int(d % time.Hour / time.Minute)
+	s := int(d % time.Minute / time.Second)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
 	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
 
-	return ""
+type encounterTickMsg struct {
+	id int
+	t  time.Time
+}
+
+// startTimer starts ticking every second to keep the timers up to date,
+// stopping any previous ticks.
+func (e *encounter) startTimer() tea.Cmd {
+	e.tick++
+	e.lastTick = time.Now()
+	return e.nextTick()
+}
+
+func (e encounter) nextTick() tea.Cmd {
+	id := e.tick
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return encounterTickMsg{id: id, t: t}
+	})
+}
+
+// refreshList rebuilds the list items from the initiative groups, marking the
+// group whose turn it is.
+func (e *encounter) refreshList() {
+	e.list.SetItems(initiativeGroupItems(e.Encounter))
+}
+
+// resume picks the interrupted encounter up where it left off. The turn's
+// timer starts over, so that the time the program wasn't running doesn't
+// count towards it.
+func (e *encounter) resume() tea.Cmd {
+	e.Encounter = *e.interrupted
+	e.interrupted = nil
+	e.placeholderKeys.resume.SetEnabled(false)
+	e.TurnStartedAt = time.Now()
+
+	e.refreshList()
+	e.list.Select(max(e.Turn, 0))
+	e.view = encounterDetail
+	e.notify()
+	return e.startTimer()
+}
+
+// notify journals the current encounter, so that it can be resumed if the
+// program exits before it ends, and passes it to the observer, if any.
+func (e *encounter) notify() {
+	var err error
+	if e.ID == "" {
+		err = e.store.ClearJournal()
+	} else {
+		err = e.store.Journal(e.Encounter)
+	}
+	if err != nil {
+		e.err = err
+	}
+
+	if e.observer != nil {
+		e.observer(e.Encounter)
+	}
 }
 
 // Messages
-type startEncounterCreateMsg struct{}
+// startEncounterCreateMsg opens the encounter creation form, filled in with
+// a random encounter's summary, monsters and game system when it has them.
+type startEncounterCreateMsg struct {
+	summary  string
+	monsters string
+	system   GameSystem
+}
 type cancelEncounterCreationMsg struct{}
 
 // Key mappings
 type encounterPlaceholderKeyMap struct {
 	startEncounter key.Binding
+	random         key.Binding
+	resume         key.Binding
 }
 
-func newEncounterPlaceholderKeyMap() encounterPlaceholderKeyMap {
+func newEncounterPlaceholderKeyMap(keys KeyBindings) encounterPlaceholderKeyMap {
 	return encounterPlaceholderKeyMap{
-		startEncounter: key.NewBinding(
-			key.WithKeys("n"),
-			key.WithHelp("n", "new encounter"),
-		),
+		startEncounter: keys.get("encounter.new"),
+		random:         keys.get("encounter.random"),
+		resume:         keys.get("encounter.resume"),
 	}
 }
 
 func (k encounterPlaceholderKeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.startEncounter}
+	return []key.Binding{k.startEncounter, k.random, k.resume}
 }
 
 func (k encounterPlaceholderKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.startEncounter},
+		{k.startEncounter, k.random, k.resume},
 	}
 }
 
 type encounterDetailKeyMap struct {
-	back key.Binding
+	nextTurn     key.Binding
+	previousTurn key.Binding
+	moveUp       key.Binding
+	moveDown     key.Binding
+	merge        key.Binding
+	split        key.Binding
+	damage       key.Binding
+	heal         key.Binding
+	hp           key.Binding
+	condition    key.Binding
+	notes        key.Binding
+	statBlock    key.Binding
+	wait         key.Binding
+	rejoin       key.Binding
+	stats        key.Binding
+	palette      key.Binding
+	back         key.Binding
 }
 
-func newEncounterDetailKeyMap() encounterDetailKeyMap {
+func newEncounterDetailKeyMap(keys KeyBindings) encounterDetailKeyMap {
 	return encounterDetailKeyMap{
-		back: key.NewBinding(
-			key.WithKeys("esc"),
-			key.WithHelp("esc", "stop encounter"),
-		),
+		nextTurn:     keys.get("encounter.next-turn"),
+		previousTurn: keys.get("encounter.previous-turn"),
+		moveUp:       keys.get("encounter.move-up"),
+		moveDown:     keys.get("encounter.move-down"),
+		merge:        keys.get("encounter.merge"),
+		split:        keys.get("encounter.split"),
+		damage:       keys.get("encounter.damage"),
+		heal:         keys.get("encounter.heal"),
+		hp:           keys.get("encounter.hp"),
+		condition:    keys.get("encounter.condition"),
+		notes:        keys.get("encounter.notes"),
+		statBlock:    keys.get("encounter.stat-block"),
+		wait:         keys.get("encounter.wait"),
+		rejoin:       keys.get("encounter.rejoin"),
+		stats:        keys.get("encounter.stats"),
+		palette:      keys.get("encounter.palette"),
+		back:         keys.get("encounter.stop"),
 	}
 }
 
 func (k encounterDetailKeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.back}
+	return []key.Binding{k.nextTurn, k.previousTurn, k.damage, k.heal, k.wait, k.rejoin, k.stats, k.back}
 }
 
 func (k encounterDetailKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.back},
+		{k.nextTurn, k.previousTurn, k.palette, k.back},
+		{k.damage, k.heal, k.hp, k.condition, k.stats},
+		{k.wait, k.rejoin, k.notes, k.statBlock},
+		{k.moveUp, k.moveDown, k.merge, k.split},
 	}
 }
 
@@ -250,7 +1754,56 @@ func (k encounterDetailKeyMap) FullHelp() [][]key.Binding {
 var _ list.Item = (*initiativeGroupItem)(nil)
 
 type initiativeGroupItem struct {
-	group IniativeGroup
+	group  IniativeGroup
+	active bool
+	// averageTurns holds the average turn duration of the group's creatures
+	// that have taken a turn, by creature ID
+	averageTurns map[string]time.Duration
+	// surprised holds the IDs of the group's surprised creatures
+	surprised []string
+	// status replaces the group's initiative, such as whether it has acted
+	// in popcorn initiative
+	status string
+	// notes holds the hit points and conditions of the group's creatures, by
+	// creature ID
+	notes map[string][]string
+	// tracked holds the IDs of the creatures whose hit points are tracked,
+	// which come first in their notes
+	tracked map[string]bool
+}
+
+func initiativeGroupItems(e Encounter) []list.Item {
+	items := []list.Item{}
+	for i, group := range e.IniativeGroups {
+		averageTurns := map[string]time.Duration{}
+		surprised := []string{}
+		notes := map[string][]string{}
+		tracked := map[string]bool{}
+		for _, creature := range group.Creatures {
+			if average, ok := e.AverageTurnDuration(creature.ID()); ok {
+				averageTurns[creature.ID()] = average
+			}
+			if e.IsSurprised(creature.ID()) {
+				surprised = append(surprised, creature.ID())
+			}
+			if h, ok := e.Health[creature.ID()]; ok {
+				notes[creature.ID()] = append(notes[creature.ID()], h.describe(e.Rules()))
+				tracked[creature.ID()] = true
+			}
+			for _, condition := range e.Conditions[creature.ID()] {
+				notes[creature.ID()] = append(notes[creature.ID()], condition.String())
+			}
+		}
+		status := ""
+		if e.Mode == PopcornInitiative {
+			status = "Yet to act"
+			if e.HasActed(i) {
+				status = "Acted"
+			}
+		}
+		items = append(items, initiativeGroupItem{group: group, active: i == e.Turn && !e.Ended(), averageTurns: averageTurns, surprised: surprised, status: status, notes: notes, tracked: tracked})
+	}
+	return items
 }
 
 func (i initiativeGroupItem) FilterValue() string {
@@ -260,8 +1813,48 @@ func (i initiativeGroupItem) FilterValue() string {
 	return fmt.Sprintf("Initiative: %d", i.group.Iniative)
 }
 
+// creatureLabel returns how a creature of the group is listed: its name,
+// followed by its average turn, whether it's surprised and its notes.
+func (i initiativeGroupItem) creatureLabel(creature Creature) string {
+	label := creature.Name()
+	if average, ok := i.averageTurns[creature.ID()]; ok {
+		label += fmt.Sprintf(" (avg turn %s)", formatDuration(average))
+	}
+	if slices.Contains(i.surprised, creature.ID()) {
+		label += " (surprised)"
+	}
+	if notes := i.notes[creature.ID()]; len(notes) > 0 {
+		label += " [" + strings.Join(notes, ", ") + "]"
+	}
+	return label
+}
+
+// hitPointsAt returns the ID of the creature whose hit points are drawn at
+// column x of the item's creatures line, or empty if none are.
+func (i initiativeGroupItem) hitPointsAt(x int, selected bool) string {
+	// creatures are indented by two spaces past the item's padding, which
+	// is narrower when the item is selected
+	column := 6
+	if selected {
+		column = 4
+	}
+	for _, creature := range i.group.Creatures {
+		label := i.creatureLabel(creature)
+		if notes := i.notes[creature.ID()]; i.tracked[creature.ID()] {
+			start := column + lipgloss.Width(label) - lipgloss.Width(" ["+strings.Join(notes, ", ")+"]") + 2
+			if x >= start && x < start+lipgloss.Width(notes[0]) {
+				return creature.ID()
+			}
+		}
+		column += lipgloss.Width(label + ", ")
+	}
+	return ""
+}
+
 // List delegate for initiative groups
-type initiativeGroupItemDelegate struct{}
+type initiativeGroupItemDelegate struct {
+	theme *Theme
+}
 
 func (d initiativeGroupItemDelegate) Height() int  { return 2 }
 func (d initiativeGroupItemDelegate) Spacing() int { return 1 }
@@ -275,25 +1868,33 @@ func (d initiativeGroupItemDelegate) Render(w io.Writer, m list.Model, index int
 		return
 	}
 
-	// Initiative value styling
-	initiativeText := "Initiative: TBD"
-	if i.group.Iniative > 0 {
-		initiativeText = fmt.Sprintf("Initiative: %d", i.group.Iniative)
+	// Initiative value styling. Initiatives can be zero or negative with
+	// low modifiers; only popcorn initiative has none, and shows the status
+	// instead.
+	initiativeText := fmt.Sprintf("Initiative: %d", i.group.Iniative)
+	if i.status != "" {
+		initiativeText = i.status
+	}
+	if i.group.Side != "" {
+		initiativeText = i.group.Side + " · " + initiativeText
+	}
+	if i.active {
+		initiativeText += " ◀ current turn"
 	}
 
 	initiativeStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("214"))
+		Foreground(d.theme.initiative)
 
 	// Creatures list
 	creatureNames := []string{}
 	for _, creature := range i.group.Creatures {
-		creatureNames = append(creatureNames, creature.Name())
+		creatureNames = append(creatureNames, i.creatureLabel(creature))
 	}
 	creaturesText := strings.Join(creatureNames, ", ")
 
 	creatureStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("252"))
+		Foreground(d.theme.text)
 
 	// Combine text
 	content := initiativeStyle.Render(initiativeText) + "\n" +
@@ -305,7 +1906,7 @@ func (d initiativeGroupItemDelegate) Render(w io.Writer, m list.Model, index int
 		fn = func(s ...string) string {
 			return lipgloss.NewStyle().
 				PaddingLeft(2).
-				Foreground(lipgloss.Color("170")).
+				Foreground(d.theme.selected).
 				Render("> " + strings.Join(s, " "))
 		}
 	}
@@ -319,6 +1920,7 @@ type encounterCreationStep int
 const (
 	stepSummaryAndCharacters encounterCreationStep = iota
 	stepGatheringInitiative
+	stepSwappingInitiative
 	stepComplete
 )
 
@@ -327,40 +1929,55 @@ type encounterCreationForm struct {
 	form     *huh.Form
 	skeleton *skeleton.Skeleton
 	party    *map[string]Character
+	// version is the version of the store, which changes when the party is
+	// reloaded from the data file
+	version *int
+	keyMap  *huh.KeyMap
+	theme   *Theme
+	// offered are the IDs of the characters offered so far, to select
+	// characters added to the party while the form is open
+	offered map[string]bool
+
+	// monsterList is what the monsters are entered as, filled in by a
+	// random encounter
+	monsterList string
+	// bestiary gives monsters their armor class and stat block
+	bestiary *Bestiary
 
 	// Form data
 	summary                string
+	turnLimit              time.Duration
+	system                 GameSystem
 	selectedCharacterUUIDs []string
+	monsterGroups          []monsterGroup
+	mode                   InitiativeMode
+	reroll                 RerollMode
 	currentInitiativeIndex int
 	initiativeGroups       []IniativeGroup
+	surprised              []string
+	surpriseRules          SurpriseRules
 }
 
-func newEncounterCreateForm(skeleton *skeleton.Skeleton, party *map[string]Character) *encounterCreationForm {
+func newEncounterCreateForm(skeleton *skeleton.Skeleton, party *map[string]Character, version *int, keys KeyBindings, theme *Theme, turnLimit time.Duration, system GameSystem) *encounterCreationForm {
 	return &encounterCreationForm{
+		turnLimit:        turnLimit,
+		system:           system,
 		step:             stepSummaryAndCharacters,
 		skeleton:         skeleton,
 		party:            party,
+		version:          version,
+		offered:          map[string]bool{},
+		keyMap:           customFormKeyMap(keys),
+		theme:            theme,
 		initiativeGroups: []IniativeGroup{},
 	}
 }
 
-func customFormTheme() *huh.Theme {
-	theme := huh.ThemeCharm()
-
-	// Modify the focused error message to remove leading space and set red foreground
-	theme.Focused.ErrorMessage = lipgloss.NewStyle().SetString("*").Foreground(lipgloss.AdaptiveColor{Dark: "#ff5555"})
-
-	return theme
-}
-
-func customFormKeyMap() *huh.KeyMap {
+func customFormKeyMap(keys KeyBindings) *huh.KeyMap {
 	keyMap := huh.NewDefaultKeyMap()
 
 	// Add ESC key to quit the form
-	keyMap.Quit = key.NewBinding(
-		key.WithKeys("esc"),
-		key.WithHelp("esc", "exit"),
-	)
+	keyMap.Quit = keys.get("form.exit")
 
 	// Ensure help is enabled for the quit binding
 	keyMap.Quit.SetEnabled(true)
@@ -373,15 +1990,112 @@ func (f *encounterCreationForm) Init() tea.Cmd {
 	return f.form.Init()
 }
 
-func (f *encounterCreationForm) createSummaryForm() {
-	var characterOptions []huh.Option[string]
+// parseTurnLimit parses a turn limit entered in the form, where nothing
+// means no limit.
+func parseTurnLimit(str string) (time.Duration, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, nil
+	}
+	limit, err := time.ParseDuration(str)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid turn limit %q", str)
+	}
+	return limit, nil
+}
+
+// monsterGroup is a kind of monster added to the encounter. Monsters of the
+// same kind share an initiative.
+type monsterGroup struct {
+	name       string
+	initiative Initiative
+	monsters   []Creature
+}
+
+var monsterPattern = regexp.MustCompile(`^(.*?)(?:\s+[x×](\d+))?(?:\s+([+-]\d+))?$`)
+
+// parseMonsters parses the monsters entered in the form, such as
+// "Goblin x3 +2, Ogre -1", numbering monsters of the same kind. The number
+// after the count is what they add to initiative.
+func parseMonsters(str string) ([]monsterGroup, error) {
+	groups := []monsterGroup{}
+	for _, entry := range strings.Split(str, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		match := monsterPattern.FindStringSubmatch(entry)
+		name, count := strings.TrimSpace(match[1]), 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+		if name == "" || count < 1 {
+			return nil, fmt.Errorf("invalid monsters %q", entry)
+		}
+
+		group := monsterGroup{name: name}
+		if match[3] != "" {
+			group.initiative.Modifier, _ = strconv.Atoi(match[3])
+		}
+		for i := range count {
+			monster := Monster{id: uuid.New().String(), name: name, initiative: group.initiative}
+			if count > 1 {
+				monster.name = fmt.Sprintf("%s %d", name, i+1)
+			}
+			group.monsters = append(group.monsters, monster)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (g monsterGroup) String() string {
+	if len(g.monsters) > 1 {
+		return fmt.Sprintf("%s ×%d", g.name, len(g.monsters))
+	}
+	return g.name
+}
 
-	if f.party != nil {
-		for uuid, character := range *f.party {
-			characterOptions = append(characterOptions,
-				huh.NewOption(character.Name(), uuid).Selected(true),
-			)
+// validateInitiative validates an initiative entered in the form between
+// lowest and highest, where nothing means it's rolled.
+func validateInitiative(lowest, highest int) func(string) error {
+	return func(str string) error {
+		if strings.TrimSpace(str) == "" {
+			return nil
 		}
+		value, err := strconv.Atoi(strings.TrimSpace(str))
+		if err != nil {
+			return fmt.Errorf("Initiative must be a number")
+		}
+		if value < lowest || value > highest {
+			return fmt.Errorf("Initiative must be between %d and %d", lowest, highest)
+		}
+		return nil
+	}
+}
+
+// parseInitiative parses an initiative entered in the form, rolling it with
+// roll when left blank.
+func parseInitiative(str string, roll func() int) int {
+	// validation already ensures anything but a blank is a number
+	value, err := strconv.Atoi(strings.TrimSpace(str))
+	if err != nil {
+		return roll()
+	}
+	return value
+}
+
+func (f *encounterCreationForm) createSummaryForm() {
+	summary, monsters := f.summary, f.monsterList
+	turnLimit := ""
+	if f.turnLimit > 0 {
+		turnLimit = f.turnLimit.String()
+	}
+
+	systems := []huh.Option[GameSystem]{}
+	for _, system := range gameSystems {
+		systems = append(systems, huh.NewOption(system.Title(), system))
 	}
 
 	f.form = huh.NewForm(
@@ -389,60 +2103,266 @@ func (f *encounterCreationForm) createSummaryForm() {
 			huh.NewInput().
 				Key("summary").
 				Title("Summary").
+				Value(&summary).
 				Validate(func(str string) error {
 					if strings.TrimSpace(str) == "" {
 						return fmt.Errorf("Summary is required")
 					}
 					return nil
 				}).Inline(true),
+			huh.NewInput().
+				Key("turnLimit").
+				Title("Turn limit").
+				Placeholder("none, e.g. 1m30s").
+				Value(&turnLimit).
+				Validate(func(str string) error {
+					if _, err := parseTurnLimit(str); err != nil {
+						return fmt.Errorf("Turn limit must be a duration like 90s or 1m30s")
+					}
+					return nil
+				}).Inline(true),
+			huh.NewSelect[GameSystem]().
+				Key("system").
+				Title("Game system").
+				Options(systems...).
+				Value(&f.system),
 			huh.NewMultiSelect[string]().
 				Key("characters").
 				Title("Characters").
-				Options(characterOptions...),
+				OptionsFunc(f.characterOptions, f.version).
+				Height(f.characterHeight()),
+			huh.NewInput().
+				Key("monsters").
+				Title("Monsters").
+				Placeholder("e.g. Goblin x3 +2, Ogre").
+				Value(&monsters).
+				Validate(func(str string) error {
+					if _, err := parseMonsters(str); err != nil {
+						return fmt.Errorf("Monsters must be listed like Goblin x3 +2, Ogre")
+					}
+					return nil
+				}),
+			huh.NewSelect[InitiativeMode]().
+				Key("mode").
+				Title("Initiative").
+				Options(
+					huh.NewOption("Individual", IndividualInitiative),
+					huh.NewOption("Side: party against monsters", SideInitiative),
+					huh.NewOption("Popcorn: whoever acts picks who goes next", PopcornInitiative),
+				),
+			huh.NewSelect[RerollMode]().
+				Key("reroll").
+				Title("Re-roll initiative").
+				Options(
+					huh.NewOption("Never", RerollNever),
+					huh.NewOption("Every round", RerollAuto),
+					huh.NewOption("Every round, asking for speed modifiers", RerollPrompt),
+				),
 		),
-	)
+	).WithKeyMap(f.keyMap).WithTheme(f.theme.formTheme())
+}
+
+// characterOptions offers the party's characters, selecting every one that
+// wasn't offered before. It's called again whenever the party is reloaded.
+func (f *encounterCreationForm) characterOptions() []huh.Option[string] {
+	options := []huh.Option[string]{}
+	if f.party == nil {
+		return options
+	}
+	for _, character := range sortedCharacters(*f.party) {
+		options = append(options, huh.NewOption(character.Name(), character.ID()).Selected(!f.offered[character.ID()]))
+		f.offered[character.ID()] = true
+	}
+	return options
+}
+
+// characterHeight fits the characters of the party when the form opens.
+// Characters added to the party later on scroll into view.
+func (f *encounterCreationForm) characterHeight() int {
+	if f.party == nil {
+		return 2
+	}
+	// the title, then a line per character
+	return 1 + max(len(*f.party), 1)
+}
+
+// characters returns the selected characters.
+func (f *encounterCreationForm) characters() []Creature {
+	characters := []Creature{}
+	for _, uuid := range f.selectedCharacterUUIDs {
+		if character, exists := (*f.party)[uuid]; exists {
+			characters = append(characters, character)
+		}
+	}
+	return characters
+}
+
+// monsters returns the monsters of every kind.
+func (f *encounterCreationForm) monsters() []Creature {
+	monsters := []Creature{}
+	for _, group := range f.monsterGroups {
+		monsters = append(monsters, group.monsters...)
+	}
+	return monsters
 }
 
 func (f *encounterCreationForm) createInitiativeForm() {
-	if len(f.selectedCharacterUUIDs) == 0 {
+	characters, monsters := f.characters(), f.monsters()
+	if len(characters) == 0 && len(monsters) == 0 {
 		f.step = stepComplete
 		return
 	}
 
 	// Create all initiative inputs
+	creatureOptions := []huh.Option[string]{}
+	for _, creature := range append(characters, monsters...) {
+		creatureOptions = append(creatureOptions, huh.NewOption(creature.Name(), creature.ID()))
+	}
 	fields := []huh.Field{
 		huh.NewNote().Title("Initiative"),
+		huh.NewMultiSelect[string]().
+			Key("surprised").
+			Title("Surprised").
+			Options(creatureOptions...),
+		huh.NewSelect[SurpriseRules]().
+			Key("surpriseRules").
+			Title("Surprise rules").
+			Options(
+				huh.NewOption("2014: skip their first turn", SurpriseSkipsTurn),
+				huh.NewOption("2024: disadvantage on initiative", SurpriseDisadvantage),
+			),
+	}
+
+	switch f.mode {
+	case PopcornInitiative:
+		// There's no initiative to roll
+	case SideInitiative:
+		// Each side rolls a plain d20
+		if len(characters) > 0 {
+			fields = append(fields, huh.NewInput().
+				Key("initiative_party").
+				Title("Party").
+				Description("Leave blank to roll").
+				Validate(validateInitiative(1, 20)))
+		}
+		if len(monsters) > 0 {
+			fields = append(fields, huh.NewInput().
+				Key("initiative_monsters").
+				Title("Monsters").
+				Description("Leave blank to roll").
+				Validate(validateInitiative(1, 20)))
+		}
+	default:
+		for _, creature := range characters {
+			initiative := creature.(Character).Initiative()
+			fields = append(fields, huh.NewInput().
+				Key(fmt.Sprintf("initiative_%s", creature.ID())).
+				Title(creature.Name()).
+				Description(fmt.Sprintf("%s %s, leave blank to roll", f.system.InitiativeName(), initiative)).
+				Validate(validateInitiative(initiative.Range())))
+		}
+		for i, group := range f.monsterGroups {
+			fields = append(fields, huh.NewInput().
+				Key(fmt.Sprintf("initiative_monsters_%d", i)).
+				Title(group.String()).
+				Description(fmt.Sprintf("%s %s, leave blank to roll", f.system.InitiativeName(), group.initiative)).
+				Validate(validateInitiative(group.initiative.Range())))
+		}
+	}
+
+	// Create form with group containing all fields
+	f.form = huh.NewForm(
+		huh.NewGroup(fields...),
+	).WithKeyMap(f.keyMap).WithTheme(f.theme.formTheme())
+}
+
+// createSwapForm asks each selected character that can swap initiative who,
+// if anyone, they swap with. It completes the form when nobody can swap.
+func (f *encounterCreationForm) createSwapForm() {
+	fields := []huh.Field{
+		huh.NewNote().Title("Swap initiative"),
 	}
 
 	for _, uuid := range f.selectedCharacterUUIDs {
-		var characterName string
-		if f.party != nil {
-			if character, exists := (*f.party)[uuid]; exists {
-				characterName = character.Name()
+		character, exists := (*f.party)[uuid]
+		if !exists || !character.Initiative().Swap {
+			continue
+		}
+
+		options := []huh.Option[string]{huh.NewOption("Nobody", "")}
+		for _, group := range f.initiativeGroups {
+			for _, ally := range group.Creatures {
+				if _, ok := ally.(Character); ok && ally.ID() != uuid {
+					options = append(options, huh.NewOption(fmt.Sprintf("%s (%d)", ally.Name(), group.Iniative), ally.ID()))
+				}
 			}
 		}
 
 		fields = append(fields,
-			huh.NewInput().
-				Key(fmt.Sprintf("initiative_%s", uuid)).
-				Title(fmt.Sprintf("%s", characterName)).
-				Validate(func(str string) error {
-					if strings.TrimSpace(str) == "" {
-						return fmt.Errorf("Initiative is required")
-					}
-					value, err := strconv.Atoi(strings.TrimSpace(str))
-					if err != nil || value <= 0 {
-						return fmt.Errorf("Initiative must be a positive number")
-					}
-					return nil
-				}),
+			huh.NewSelect[string]().
+				Key(fmt.Sprintf("swap_%s", uuid)).
+				Title(fmt.Sprintf("%s (%d) swaps with", character.Name(), f.initiativeOf(uuid))).
+				Options(options...),
 		)
 	}
 
-	// Create form with group containing all fields
+	if len(fields) == 1 {
+		f.step = stepComplete
+		return
+	}
+
 	f.form = huh.NewForm(
 		huh.NewGroup(fields...),
-	)
+	).WithKeyMap(f.keyMap).WithTheme(f.theme.formTheme())
+}
+
+// initiativeOf returns the initiative rolled by the creature with the given
+// ID.
+func (f *encounterCreationForm) initiativeOf(id string) int {
+	for _, group := range f.initiativeGroups {
+		for _, creature := range group.Creatures {
+			if creature.ID() == id {
+				return group.Iniative
+			}
+		}
+	}
+	return 0
+}
+
+// swapInitiative swaps the initiative of the creatures with the given IDs.
+func (f *encounterCreationForm) swapInitiative(a, b string) {
+	ia, ib := -1, -1
+	for i, group := range f.initiativeGroups {
+		for _, creature := range group.Creatures {
+			switch creature.ID() {
+			case a:
+				ia = i
+			case b:
+				ib = i
+			}
+		}
+	}
+	if ia < 0 || ib < 0 {
+		return
+	}
+	f.initiativeGroups[ia].Iniative, f.initiativeGroups[ib].Iniative = f.initiativeGroups[ib].Iniative, f.initiativeGroups[ia].Iniative
+}
+
+// complete finishes the form, creating the encounter.
+func (f *encounterCreationForm) complete() tea.Cmd {
+	f.step = stepComplete
+	return func() tea.Msg {
+		return createEncounterMsg{
+			summary:          f.summary,
+			turnLimit:        f.turnLimit,
+			initiativeGroups: f.initiativeGroups,
+			surprised:        f.surprised,
+			surpriseRules:    f.surpriseRules,
+			mode:             f.mode,
+			reroll:           f.reroll,
+			system:           f.system,
+		}
+	}
 }
 
 func (f *encounterCreationForm) Update(msg tea.Msg) (*encounterCreationForm, tea.Cmd) {
@@ -466,52 +2386,108 @@ func (f *encounterCreationForm) Update(msg tea.Msg) (*encounterCreationForm, tea
 		switch f.step {
 		case stepSummaryAndCharacters:
 			f.summary = f.form.GetString("summary")
+			// validation already ensures the turn limit can be parsed
+			f.turnLimit, _ = parseTurnLimit(f.form.GetString("turnLimit"))
 			f.selectedCharacterUUIDs = f.form.Get("characters").([]string)
+			f.mode = f.form.Get("mode").(InitiativeMode)
+			f.reroll = f.form.Get("reroll").(RerollMode)
+			f.system = f.form.Get("system").(GameSystem)
+			// validation already ensures the monsters can be parsed
+			f.monsterGroups, _ = parseMonsters(f.form.GetString("monsters"))
+			if f.bestiary != nil {
+				f.bestiary.fillStats(f.system, f.monsterGroups)
+			}
 			f.step = stepGatheringInitiative
 			f.createInitiativeForm()
 			if f.step == stepComplete {
-				return f, tea.Cmd(func() tea.Msg {
-					return createEncounterMsg{
-						summary:          f.summary,
-						initiativeGroups: f.initiativeGroups,
-					}
-				})
+				return f, f.complete()
 			}
 			return f, f.form.Init()
 
 		case stepGatheringInitiative:
-			// Parse all initiative values
-			for _, uuid := range f.selectedCharacterUUIDs {
-				initiativeKey := fmt.Sprintf("initiative_%s", uuid)
-				initiativeStr := f.form.GetString(initiativeKey)
+			f.surprised = f.form.Get("surprised").([]string)
+			f.surpriseRules = f.form.Get("surpriseRules").(SurpriseRules)
+
+			// surprised reports whether any of the creatures rolls initiative
+			// with disadvantage for being surprised
+			surprised := func(creatures ...Creature) bool {
+				return f.surpriseRules == SurpriseDisadvantage && slices.ContainsFunc(creatures, func(c Creature) bool {
+					return slices.Contains(f.surprised, c.ID())
+				})
+			}
 
-				// Parse initiative value (validation already ensures it's a positive integer)
-				initiativeValue, err := strconv.Atoi(strings.TrimSpace(initiativeStr))
-				if err != nil {
-					// This shouldn't happen due to validation, but default to 1
-					initiativeValue = 1
+			if f.mode == PopcornInitiative {
+				for _, creature := range f.characters() {
+					f.initiativeGroups = append(f.initiativeGroups, IniativeGroup{Creatures: []Creature{creature}})
 				}
+				for _, group := range f.monsterGroups {
+					f.initiativeGroups = append(f.initiativeGroups, IniativeGroup{Creatures: group.monsters})
+				}
+				return f, f.complete()
+			}
 
-				// Get character and create initiative group
-				if f.party != nil {
-					if character, exists := (*f.party)[uuid]; exists {
-						group := IniativeGroup{
-							Iniative:  initiativeValue,
-							Creatures: []Creature{character},
-						}
-						f.initiativeGroups = append(f.initiativeGroups, group)
+			if f.mode == SideInitiative {
+				for _, side := range []struct {
+					name      string
+					key       string
+					creatures []Creature
+				}{
+					{"Party", "initiative_party", f.characters()},
+					{"Monsters", "initiative_monsters", f.monsters()},
+				} {
+					if len(side.creatures) == 0 {
+						continue
 					}
+					initiative := parseInitiative(f.form.GetString(side.key), func() int {
+						return f.system.RollInitiative(Initiative{}, surprised(side.creatures...))
+					})
+					f.initiativeGroups = append(f.initiativeGroups, IniativeGroup{
+						Iniative:  initiative,
+						Creatures: side.creatures,
+						Side:      side.name,
+					})
 				}
+				return f, f.complete()
+			}
+
+			// Parse all initiative values
+			for _, creature := range f.characters() {
+				character := creature.(Character)
+				initiative := parseInitiative(f.form.GetString(fmt.Sprintf("initiative_%s", character.ID())), func() int {
+					return f.system.RollInitiative(character.Initiative(), surprised(character))
+				})
+				f.initiativeGroups = append(f.initiativeGroups, IniativeGroup{
+					Iniative:  initiative,
+					Creatures: []Creature{character},
+				})
+			}
+			for i, group := range f.monsterGroups {
+				initiative := parseInitiative(f.form.GetString(fmt.Sprintf("initiative_monsters_%d", i)), func() int {
+					return f.system.RollInitiative(group.initiative, surprised(group.monsters...))
+				})
+				f.initiativeGroups = append(f.initiativeGroups, IniativeGroup{
+					Iniative:  initiative,
+					Creatures: group.monsters,
+				})
+			}
+
+			// All initiatives processed, let characters swap them
+			f.step = stepSwappingInitiative
+			f.createSwapForm()
+			if f.step == stepComplete {
+				return f, f.complete()
 			}
+			return f, f.form.Init()
 
-			// All initiatives processed, complete the form
-			f.step = stepComplete
-			return f, tea.Cmd(func() tea.Msg {
-				return createEncounterMsg{
-					summary:          f.summary,
-					initiativeGroups: f.initiativeGroups,
+		case stepSwappingInitiative:
+			// Swaps are applied one after the other, in the order the
+			// characters were listed
+			for _, uuid := range f.selectedCharacterUUIDs {
+				if ally, ok := f.form.Get(fmt.Sprintf("swap_%s", uuid)).(string); ok && ally != "" {
+					f.swapInitiative(uuid, ally)
 				}
-			})
+			}
+			return f, f.complete()
 		}
 	}
 
@@ -539,5 +2515,11 @@ func (f *encounterCreationForm) View() string {
 
 type createEncounterMsg struct {
 	summary          string
+	turnLimit        time.Duration
 	initiativeGroups []IniativeGroup
+	surprised        []string
+	surpriseRules    SurpriseRules
+	mode             InitiativeMode
+	reroll           RerollMode
+	system           GameSystem
 }
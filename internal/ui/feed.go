@@ -0,0 +1,56 @@
+package ui
+
+import "sync"
+
+// EncounterFeed fans the running encounter out to any number of subscribers,
+// such as read-only player views running in other programs. Its Publish
+// method can be registered with WithEncounterObserver.
+type EncounterFeed struct {
+	mu      sync.Mutex
+	current Encounter
+	subs    map[chan Encounter]struct{}
+}
+
+func NewEncounterFeed() *EncounterFeed {
+	return &EncounterFeed{subs: map[chan Encounter]struct{}{}}
+}
+
+// Publish sends a copy of e to every subscriber. Subscribers that haven't
+// received the previous encounter yet only get the latest one.
+func (f *EncounterFeed) Publish(e Encounter) {
+	e = e.clone()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.current = e
+	for ch := range f.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- e
+	}
+}
+
+// Subscribe returns a channel receiving every published encounter, starting
+// with the current one, and a function to cancel the subscription.
+func (f *EncounterFeed) Subscribe() (<-chan Encounter, func()) {
+	ch := make(chan Encounter, 1)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.subs[ch] = struct{}{}
+	ch <- f.current
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			delete(f.subs, ch)
+			close(ch)
+		})
+	}
+}
@@ -1,41 +1,910 @@
 package ui
 
-import "time"
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
 
 type Encounter struct {
+	// ID uniquely identifies the encounter in the history.
+	ID      string
 	Summary string
+	// Notes are free-form Markdown notes about the encounter, such as
+	// tactics and reminders.
+	Notes string
 
 	StartedAt time.Time
 	EndedAt   time.Time
 
 	IniativeGroups []IniativeGroup
+
+	// Round is the current round of combat, starting at 1.
+	Round int
+	// Turn is the index of the initiative group whose turn it is.
+	Turn int
+
+	// TurnStartedAt is when the current turn started.
+	TurnStartedAt time.Time
+	// TurnLimit is how long a turn may take before it's flagged as taking
+	// too long, or zero for no limit.
+	TurnLimit time.Duration
+	// TurnDurations holds how long each turn a creature took lasted, by
+	// creature ID.
+	TurnDurations map[string][]time.Duration
+
+	// Log holds the damage and healing done during the encounter, in the
+	// order it happened.
+	Log []CombatEvent
+
+	// Waiting holds the creatures that left the initiative order to delay or
+	// ready an action, in the order they left.
+	Waiting []WaitingCreature
+
+	// Surprised holds the IDs of the creatures that are surprised until the
+	// end of their first turn.
+	Surprised []string
+	// SurpriseRules is how surprise affects the surprised creatures.
+	SurpriseRules SurpriseRules
+
+	// Mode is how initiative was rolled for the encounter.
+	Mode InitiativeMode
+	// Reroll is whether initiative is rolled again at the end of every
+	// round.
+	Reroll RerollMode
+	// Acted holds the IDs of the creatures that have acted this round in
+	// popcorn initiative.
+	Acted []string
+
+	// System is the game system whose rules the encounter follows, the
+	// default game system when nil.
+	System GameSystem
+	// Health holds the hit points of the creatures whose hit points are
+	// tracked, by creature ID.
+	Health map[string]Health
+	// Conditions holds the conditions creatures have, by creature ID.
+	Conditions map[string][]Condition
+}
+
+type RerollMode int
+
+const (
+	// RerollNever keeps the initiative order for the whole encounter.
+	RerollNever RerollMode = iota
+	// RerollAuto rolls new initiatives at the end of every round.
+	RerollAuto
+	// RerollPrompt asks for new initiatives, or the speed modifiers to roll
+	// them with, at the end of every round.
+	RerollPrompt
+)
+
+type InitiativeMode int
+
+const (
+	// IndividualInitiative gives every character, and every kind of monster,
+	// its own initiative.
+	IndividualInitiative InitiativeMode = iota
+	// SideInitiative rolls initiative once per side, the party acting as one
+	// group and the monsters as another, as in the DMG variant.
+	SideInitiative
+	// PopcornInitiative has no initiative order: at the end of each turn
+	// the acting creature picks who goes next from those who haven't acted
+	// this round.
+	PopcornInitiative
+)
+
+type SurpriseRules int
+
+const (
+	// SurpriseSkipsTurn skips the first turn of surprised creatures, as in
+	// the 2014 rules.
+	SurpriseSkipsTurn SurpriseRules = iota
+	// SurpriseDisadvantage gives surprised creatures disadvantage on
+	// initiative, as in the 2024 rules.
+	SurpriseDisadvantage
+)
+
+type WaitAction int
+
+const (
+	Delay WaitAction = iota
+	Ready
+)
+
+func (a WaitAction) String() string {
+	if a == Ready {
+		return "readied an action"
+	}
+	return "delaying"
+}
+
+// WaitingCreature is a creature that left the initiative order until it
+// rejoins.
+type WaitingCreature struct {
+	Creature Creature
+	Action   WaitAction
+	// Initiative is the creature's initiative before it left the order.
+	Initiative int
+}
+
+type CombatEventKind int
+
+const (
+	DamageEvent CombatEventKind = iota
+	HealingEvent
+)
+
+// CombatEvent is damage or healing done by one creature to another.
+type CombatEvent struct {
+	Kind  CombatEventKind
+	Round int
+	// Source is the ID of the creature doing the damage or healing, or empty
+	// when it didn't come from a creature.
+	Source string
+	// Target is the ID of the creature taking the damage or healing.
+	Target string
+	Amount int
+	// DamageType is the type of the damage, such as fire, when it was given.
+	DamageType string
+	// Critical reports whether the damage came from a critical hit.
+	Critical bool
+	// Killed reports whether the damage killed the target.
+	Killed bool
+}
+
+// clone returns a copy of the encounter that shares no mutable state with e.
+func (e Encounter) clone() Encounter {
+	groups := make([]IniativeGroup, len(e.IniativeGroups))
+	for i, group := range e.IniativeGroups {
+		groups[i] = IniativeGroup{
+			Iniative:  group.Iniative,
+			Creatures: append([]Creature(nil), group.Creatures...),
+			Side:      group.Side,
+		}
+	}
+	e.IniativeGroups = groups
+
+	durations := make(map[string][]time.Duration, len(e.TurnDurations))
+	for id, d := range e.TurnDurations {
+		durations[id] = append([]time.Duration(nil), d...)
+	}
+	e.TurnDurations = durations
+
+	e.Log = append([]CombatEvent(nil), e.Log...)
+	e.Waiting = append([]WaitingCreature(nil), e.Waiting...)
+	e.Surprised = append([]string(nil), e.Surprised...)
+	e.Acted = append([]string(nil), e.Acted...)
+
+	health := make(map[string]Health, len(e.Health))
+	for id, h := range e.Health {
+		health[id] = h
+	}
+	e.Health = health
+
+	conditions := make(map[string][]Condition, len(e.Conditions))
+	for id, c := range e.Conditions {
+		conditions[id] = append([]Condition(nil), c...)
+	}
+	e.Conditions = conditions
+
+	return e
+}
+
+// Rules returns the game system the encounter follows.
+func (e Encounter) Rules() GameSystem {
+	if e.System == nil {
+		return DefaultGameSystem()
+	}
+	return e.System
+}
+
+// Duration returns how long the encounter has been running at now, or how
+// long it lasted once it has ended.
+func (e Encounter) Duration(now time.Time) time.Duration {
+	if e.StartedAt.IsZero() {
+		return 0
+	}
+	if !e.EndedAt.IsZero() {
+		return e.EndedAt.Sub(e.StartedAt)
+	}
+	return now.Sub(e.StartedAt)
+}
+
+// TurnElapsed returns how long the current turn has been running at now.
+func (e Encounter) TurnElapsed(now time.Time) time.Duration {
+	if e.TurnStartedAt.IsZero() {
+		return 0
+	}
+	return now.Sub(e.TurnStartedAt)
+}
+
+// TurnOverLimit reports whether the current turn has taken longer than the
+// turn limit at now.
+func (e Encounter) TurnOverLimit(now time.Time) bool {
+	return e.TurnLimit > 0 && e.TurnElapsed(now) > e.TurnLimit
+}
+
+// AverageTurnDuration returns the average duration of the turns taken by the
+// creature with the given ID, and false if it hasn't finished a turn yet.
+func (e Encounter) AverageTurnDuration(id string) (time.Duration, bool) {
+	durations := e.TurnDurations[id]
+	if len(durations) == 0 {
+		return 0, false
+	}
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations)), true
+}
+
+// ActiveGroup returns the initiative group whose turn it is.
+func (e Encounter) ActiveGroup() (IniativeGroup, bool) {
+	if e.Turn < 0 || e.Turn >= len(e.IniativeGroups) {
+		return IniativeGroup{}, false
+	}
+	return e.IniativeGroups[e.Turn], true
+}
+
+// NextTurn advances to the next initiative group, starting a new round once
+// every group has acted. The duration of the turn that ended is recorded for
+// every creature in its group. In popcorn initiative the turn passes to the
+// first group that can go next.
+func (e *Encounter) NextTurn() {
+	if len(e.IniativeGroups) == 0 {
+		return
+	}
+	if e.Mode == PopcornInitiative {
+		e.PassTurn(e.NextCandidates()[0])
+		return
+	}
+
+	now := time.Now()
+	e.recordTurn(now)
+	e.endSurprise()
+	e.endConditions()
+	e.TurnStartedAt = now
+
+	e.advance()
+	e.skipSurprised()
+}
+
+// HasActed reports whether every creature in the group at index i has acted
+// this round in popcorn initiative.
+func (e Encounter) HasActed(i int) bool {
+	if i < 0 || i >= len(e.IniativeGroups) || len(e.IniativeGroups[i].Creatures) == 0 {
+		return false
+	}
+	return !slices.ContainsFunc(e.IniativeGroups[i].Creatures, func(c Creature) bool {
+		return !slices.Contains(e.Acted, c.ID())
+	})
+}
+
+// NextCandidates returns the indexes of the groups that can go next in
+// popcorn initiative once the current turn ends: those that haven't acted
+// this round, or every group when the round is over.
+func (e Encounter) NextCandidates() []int {
+	candidates := []int{}
+	for i := range e.IniativeGroups {
+		if i != e.Turn && !e.HasActed(i) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range e.IniativeGroups {
+			candidates = append(candidates, i)
+		}
+	}
+	return candidates
+}
+
+// PassTurn ends the current turn in popcorn initiative, passing the turn to
+// the group at index next. The round ends once every group has acted.
+func (e *Encounter) PassTurn(next int) {
+	if next < 0 || next >= len(e.IniativeGroups) {
+		return
+	}
+
+	roundOver := true
+	for i := range e.IniativeGroups {
+		if i != e.Turn && !e.HasActed(i) {
+			roundOver = false
+		}
+	}
+
+	now := time.Now()
+	e.recordTurn(now)
+	e.endSurprise()
+	e.endConditions()
+	e.TurnStartedAt = now
+
+	if group, ok := e.ActiveGroup(); ok {
+		for _, creature := range group.Creatures {
+			e.Acted = append(e.Acted, creature.ID())
+		}
+	}
+	if roundOver {
+		e.Acted = nil
+		e.Round++
+	}
+	e.Turn = next
+}
+
+// advance passes the turn to the next initiative group.
+func (e *Encounter) advance() {
+	e.Turn++
+	if e.Turn >= len(e.IniativeGroups) {
+		e.Turn = 0
+		e.Round++
+	}
+}
+
+// RollInitiatives rolls a new initiative for every group, in order.
+func (e Encounter) RollInitiatives() []int {
+	initiatives := []int{}
+	for _, group := range e.IniativeGroups {
+		initiatives = append(initiatives, e.Rules().RollInitiative(group.Modifiers(), false))
+	}
+	return initiatives
+}
+
+// SetInitiatives gives each group the initiative at the same index and
+// sorts the groups by their new initiative for the round, starting it over
+// with the first group.
+func (e *Encounter) SetInitiatives(initiatives []int) {
+	for i := range min(len(initiatives), len(e.IniativeGroups)) {
+		e.IniativeGroups[i].Iniative = initiatives[i]
+	}
+	e.sortGroups()
+	e.Turn = 0
+	e.TurnStartedAt = time.Now()
+}
+
+// sortGroups sorts the groups by initiative, highest first, breaking ties
+// by the rules of the game system.
+func (e *Encounter) sortGroups() {
+	slices.SortStableFunc(e.IniativeGroups, func(a, b IniativeGroup) int {
+		return cmp.Or(b.Iniative-a.Iniative, e.Rules().BreakTie(a, b))
+	})
+}
+
+// IsSurprised reports whether the creature with the given ID is surprised.
+func (e Encounter) IsSurprised(id string) bool {
+	return slices.Contains(e.Surprised, id)
+}
+
+// endSurprise ends the surprise of the active group's creatures, at the end
+// of their turn.
+func (e *Encounter) endSurprise() {
+	group, ok := e.ActiveGroup()
+	if !ok {
+		return
+	}
+	e.Surprised = slices.DeleteFunc(slices.Clone(e.Surprised), func(id string) bool {
+		return slices.ContainsFunc(group.Creatures, func(c Creature) bool { return c.ID() == id })
+	})
+}
+
+// endConditions applies the game system's end of turn rules to the
+// conditions of the active group's creatures, such as Frightened going down.
+func (e *Encounter) endConditions() {
+	group, ok := e.ActiveGroup()
+	if !ok {
+		return
+	}
+	for _, creature := range group.Creatures {
+		if conditions, ok := e.Conditions[creature.ID()]; ok {
+			e.SetConditions(creature.ID(), e.Rules().EndTurn(slices.Clone(conditions)))
+		}
+	}
+}
+
+// SetConditions replaces the conditions of the creature with the given ID.
+func (e *Encounter) SetConditions(id string, conditions []Condition) {
+	if e.Conditions == nil {
+		e.Conditions = map[string][]Condition{}
+	}
+	if len(conditions) == 0 {
+		delete(e.Conditions, id)
+		return
+	}
+	e.Conditions[id] = conditions
+}
+
+// ToggleCondition gives the creature with the given ID the condition, or
+// takes it away when it already has it. A valued condition is given the
+// value, replacing the value it had, and taken away when the value is zero.
+func (e *Encounter) ToggleCondition(id string, condition ConditionType, value int) {
+	conditions := slices.Clone(e.Conditions[id])
+	i := slices.IndexFunc(conditions, func(c Condition) bool { return c.Name == condition.Name })
+
+	switch {
+	case condition.Valued && value > 0 && i >= 0:
+		conditions[i].Value = value
+	case condition.Valued && value > 0, !condition.Valued && i < 0:
+		c := Condition{Name: condition.Name}
+		if condition.Valued {
+			c.Value = value
+		}
+		conditions = append(conditions, c)
+	case i >= 0:
+		conditions = slices.Delete(conditions, i, i+1)
+	}
+	e.SetConditions(id, conditions)
+}
+
+// SetHealth starts tracking the hit points of the creature with the given
+// ID, or stops tracking them when the maximum is zero.
+func (e *Encounter) SetHealth(id string, hp, maxHP int) {
+	if e.Health == nil {
+		e.Health = map[string]Health{}
+	}
+	if maxHP <= 0 {
+		delete(e.Health, id)
+		return
+	}
+	h := e.Health[id]
+	h.HP, h.MaxHP = min(max(hp, 0), maxHP), maxHP
+	if h.HP > 0 {
+		h.Dying, h.Dead = 0, false
+	}
+	e.Health[id] = h
+}
+
+// skipSurprised passes the turn on for as long as every creature in the
+// active group is surprised under the 2014 rules, ending their surprise.
+func (e *Encounter) skipSurprised() {
+	for e.SurpriseRules == SurpriseSkipsTurn && e.Mode != PopcornInitiative {
+		group, ok := e.ActiveGroup()
+		if !ok || len(group.Creatures) == 0 || slices.ContainsFunc(group.Creatures, func(c Creature) bool { return !e.IsSurprised(c.ID()) }) {
+			return
+		}
+		e.endSurprise()
+		e.advance()
+	}
+}
+
+// End ends the encounter, recording the duration of the turn in progress.
+func (e *Encounter) End() {
+	now := time.Now()
+	e.recordTurn(now)
+	e.TurnStartedAt = time.Time{}
+	e.EndedAt = now
+}
+
+// Record adds the damage or healing to the log in the current round. When
+// the target's hit points are tracked, they're updated by the rules of the
+// game system, and damage that leaves the target dead is recorded as a kill.
+func (e *Encounter) Record(event CombatEvent) {
+	event.Round = e.Round
+	if h, ok := e.Health[event.Target]; ok {
+		switch event.Kind {
+		case DamageEvent:
+			wasDead := h.Dead
+			h = e.Rules().Damage(h, e.Conditions[event.Target], event.Amount, event.Critical)
+			if event.Killed {
+				h.HP, h.Dead = 0, true
+			}
+			event.Killed = h.Dead && !wasDead
+		case HealingEvent:
+			h = e.Rules().Heal(h, event.Amount)
+		}
+		e.Health[event.Target] = h
+	}
+	e.Log = append(e.Log, event)
+}
+
+// Ended reports whether the encounter has ended.
+func (e Encounter) Ended() bool {
+	return !e.EndedAt.IsZero()
+}
+
+// recordTurn records how long the current turn lasted at now for every
+// creature in the active group.
+func (e *Encounter) recordTurn(now time.Time) {
+	group, ok := e.ActiveGroup()
+	if !ok || e.TurnStartedAt.IsZero() {
+		return
+	}
+
+	if e.TurnDurations == nil {
+		e.TurnDurations = map[string][]time.Duration{}
+	}
+	for _, creature := range group.Creatures {
+		e.TurnDurations[creature.ID()] = append(e.TurnDurations[creature.ID()], now.Sub(e.TurnStartedAt))
+	}
+}
+
+// PreviousTurn steps back to the previous initiative group, returning to the
+// previous round when moving back past the first group. The turn's timer
+// starts over. There is no previous group in popcorn initiative.
+func (e *Encounter) PreviousTurn() {
+	if len(e.IniativeGroups) == 0 || (e.Round <= 1 && e.Turn == 0) || e.Mode == PopcornInitiative {
+		return
+	}
+	e.TurnStartedAt = time.Now()
+
+	e.Turn--
+	if e.Turn < 0 {
+		e.Turn = len(e.IniativeGroups) - 1
+		e.Round--
+	}
+}
+
+// Creatures returns every creature in the encounter, in initiative order
+// followed by the creatures waiting to rejoin it.
+func (e Encounter) Creatures() []Creature {
+	creatures := []Creature{}
+	for _, group := range e.IniativeGroups {
+		creatures = append(creatures, group.Creatures...)
+	}
+	for _, waiting := range e.Waiting {
+		creatures = append(creatures, waiting.Creature)
+	}
+	return creatures
+}
+
+// SetCreatureNotes replaces the notes of the creature with the given ID,
+// wherever it is in the encounter.
+func (e *Encounter) SetCreatureNotes(id, notes string) bool {
+	return e.updateCreature(id, func(c Creature) Creature {
+		switch c := c.(type) {
+		case Character:
+			c.notes = notes
+			return c
+		case Monster:
+			c.notes = notes
+			return c
+		}
+		return c
+	})
+}
+
+// SetCreatureStats replaces the armor class and stat block of the creature
+// with the given ID, wherever it is in the encounter.
+func (e *Encounter) SetCreatureStats(id string, armorClass int, statBlock string) bool {
+	return e.updateCreature(id, func(c Creature) Creature {
+		switch c := c.(type) {
+		case Character:
+			c.armorClass, c.statBlock = armorClass, statBlock
+			return c
+		case Monster:
+			c.armorClass, c.statBlock = armorClass, statBlock
+			return c
+		}
+		return c
+	})
+}
+
+// updateCreature replaces the creature with the given ID by update's result,
+// wherever it is in the encounter, reporting whether it was found.
+func (e *Encounter) updateCreature(id string, update func(Creature) Creature) bool {
+	for i, group := range e.IniativeGroups {
+		if j := slices.IndexFunc(group.Creatures, func(c Creature) bool { return c.ID() == id }); j >= 0 {
+			e.IniativeGroups[i].Creatures = slices.Clone(group.Creatures)
+			e.IniativeGroups[i].Creatures[j] = update(group.Creatures[j])
+			return true
+		}
+	}
+	for i, waiting := range e.Waiting {
+		if waiting.Creature.ID() == id {
+			e.Waiting[i].Creature = update(waiting.Creature)
+			return true
+		}
+	}
+	return false
+}
+
+// Wait takes the creature out of the initiative order until it rejoins. When
+// that leaves the active group empty its turn ends, passing the turn to the
+// next group.
+func (e *Encounter) Wait(id string, action WaitAction) bool {
+	for i, group := range e.IniativeGroups {
+		j := slices.IndexFunc(group.Creatures, func(c Creature) bool { return c.ID() == id })
+		if j < 0 {
+			continue
+		}
+
+		e.Waiting = append(e.Waiting, WaitingCreature{
+			Creature:   group.Creatures[j],
+			Action:     action,
+			Initiative: group.Iniative,
+		})
+
+		if len(group.Creatures) > 1 {
+			e.IniativeGroups[i].Creatures = slices.Delete(slices.Clone(group.Creatures), j, j+1)
+			return true
+		}
+
+		if i == e.Turn {
+			now := time.Now()
+			e.recordTurn(now)
+			e.TurnStartedAt = now
+		}
+		e.IniativeGroups = slices.Delete(e.IniativeGroups, i, i+1)
+		if i < e.Turn {
+			e.Turn--
+		}
+		if e.Turn >= len(e.IniativeGroups) && len(e.IniativeGroups) > 0 {
+			e.Turn = 0
+			e.Round++
+		}
+		return true
+	}
+	return false
+}
+
+// Rejoin puts a waiting creature back into the initiative order in its own
+// group right after the group at index after, taking that group's
+// initiative. An index below zero puts it at the top of the order. The group
+// whose turn it is stays the same.
+func (e *Encounter) Rejoin(id string, after int) bool {
+	i := slices.IndexFunc(e.Waiting, func(w WaitingCreature) bool { return w.Creature.ID() == id })
+	if i < 0 {
+		return false
+	}
+	waiting := e.Waiting[i]
+	e.Waiting = slices.Delete(e.Waiting, i, i+1)
+
+	after = min(after, len(e.IniativeGroups)-1)
+	group := IniativeGroup{Iniative: waiting.Initiative, Creatures: []Creature{waiting.Creature}}
+	switch {
+	case after >= 0:
+		group.Iniative = e.IniativeGroups[after].Iniative
+	case len(e.IniativeGroups) > 0:
+		group.Iniative = max(group.Iniative, e.IniativeGroups[0].Iniative)
+	}
+
+	e.IniativeGroups = slices.Insert(e.IniativeGroups, after+1, group)
+	if after+1 <= e.Turn && len(e.IniativeGroups) > 1 {
+		e.Turn++
+	}
+	return true
+}
+
+// AddGroup adds a group to the initiative order after the groups with the
+// same or a higher initiative. Once the encounter has started, the group
+// whose turn it is stays the same; before that, in round 0, the turn is the
+// first group's.
+func (e *Encounter) AddGroup(group IniativeGroup) {
+	i := slices.IndexFunc(e.IniativeGroups, func(g IniativeGroup) bool { return g.Iniative < group.Iniative })
+	if i < 0 {
+		i = len(e.IniativeGroups)
+	}
+	e.IniativeGroups = slices.Insert(e.IniativeGroups, i, group)
+	if e.Round > 0 && i <= e.Turn && len(e.IniativeGroups) > 1 {
+		e.Turn++
+	}
+}
+
+// MoveGroup moves the group at index i one place up the initiative order
+// when delta is negative, or down when it's positive, returning its new
+// index. The group takes the initiative of the group it passes so that the
+// order stays sorted, and the group whose turn it is stays the same.
+func (e *Encounter) MoveGroup(i, delta int) int {
+	j := i + 1
+	if delta < 0 {
+		j = i - 1
+	}
+	if i < 0 || i >= len(e.IniativeGroups) || j < 0 || j >= len(e.IniativeGroups) {
+		return i
+	}
+
+	e.IniativeGroups[i].Iniative = e.IniativeGroups[j].Iniative
+	e.IniativeGroups[i], e.IniativeGroups[j] = e.IniativeGroups[j], e.IniativeGroups[i]
+
+	switch e.Turn {
+	case i:
+		e.Turn = j
+	case j:
+		e.Turn = i
+	}
+	return j
+}
+
+// MergeGroup merges the group at index i into the group above it, which
+// keeps its initiative. It returns the index of the merged group.
+func (e *Encounter) MergeGroup(i int) int {
+	if i <= 0 || i >= len(e.IniativeGroups) {
+		return i
+	}
+
+	above := &e.IniativeGroups[i-1]
+	above.Creatures = append(slices.Clone(above.Creatures), e.IniativeGroups[i].Creatures...)
+	e.IniativeGroups = slices.Delete(e.IniativeGroups, i, i+1)
+
+	if e.Turn >= i {
+		e.Turn--
+	}
+	return i - 1
+}
+
+// SplitGroup splits the group at index i into a group per creature with the
+// same initiative, in the order the creatures were listed.
+func (e *Encounter) SplitGroup(i int) {
+	if i < 0 || i >= len(e.IniativeGroups) || len(e.IniativeGroups[i].Creatures) < 2 {
+		return
+	}
+
+	group := e.IniativeGroups[i]
+	groups := []IniativeGroup{}
+	for _, creature := range group.Creatures {
+		groups = append(groups, IniativeGroup{Iniative: group.Iniative, Creatures: []Creature{creature}})
+	}
+	e.IniativeGroups = slices.Replace(e.IniativeGroups, i, i+1, groups...)
+
+	if e.Turn > i {
+		e.Turn += len(groups) - 1
+	}
 }
 
 type IniativeGroup struct {
 	Iniative  int
 	Creatures []Creature
+	// Side is the side the group stands for in side initiative, or empty.
+	Side string
+}
+
+// Modifiers returns what the group adds to their initiative rolls, which is
+// nothing for a side. The initiative they rolled is Iniative.
+func (g IniativeGroup) Modifiers() Initiative {
+	if g.Side != "" || len(g.Creatures) == 0 {
+		return Initiative{}
+	}
+	if c, ok := g.Creatures[0].(interface{ Initiative() Initiative }); ok {
+		return c.Initiative()
+	}
+	return Initiative{}
 }
 
 type Creature interface {
+	// ID uniquely identifies the creature across encounters.
+	ID() string
 	Name() string
+	// Notes are free-form Markdown notes about the creature.
+	Notes() string
+	// ArmorClass is the creature's armor class, or zero when it isn't
+	// known.
+	ArmorClass() int
+	// StatBlock is the creature's statistics and abilities, in Markdown.
+	StatBlock() string
 }
 
 var _ Creature = (*Monster)(nil)
 
 type Monster struct {
-	name string
+	id         string
+	name       string
+	initiative Initiative
+	notes      string
+	armorClass int
+	statBlock  string
+}
+
+func (m Monster) ID() string {
+	return m.id
 }
 
 func (m Monster) Name() string {
 	return m.name
 }
 
+func (m Monster) Notes() string {
+	return m.notes
+}
+
+func (m Monster) ArmorClass() int {
+	return m.armorClass
+}
+
+func (m Monster) StatBlock() string {
+	return m.statBlock
+}
+
+// Initiative returns what the monster adds to their initiative rolls, their
+// Dexterity modifier or Perception in PF2e.
+func (m Monster) Initiative() Initiative {
+	return m.initiative
+}
+
 var _ Creature = (*Character)(nil)
 
 type Character struct {
-	name string
+	id         string
+	name       string
+	initiative Initiative
+	notes      string
+	// level is the character's level, or zero when it isn't known
+	level      int
+	armorClass int
+	statBlock  string
+}
+
+func (c Character) ID() string {
+	return c.id
 }
 
 func (c Character) Name() string {
 	return c.name
 }
+
+func (c Character) Notes() string {
+	return c.notes
+}
+
+func (c Character) ArmorClass() int {
+	return c.armorClass
+}
+
+func (c Character) StatBlock() string {
+	return c.statBlock
+}
+
+// Initiative returns what the character adds to their initiative rolls.
+func (c Character) Initiative() Initiative {
+	return c.initiative
+}
+
+// Level returns the character's level, or zero when it isn't known.
+func (c Character) Level() int {
+	return c.level
+}
+
+// Initiative holds what a creature adds to their initiative rolls.
+type Initiative struct {
+	// Modifier is the creature's initiative modifier, usually their
+	// Dexterity modifier.
+	Modifier int
+	// Bonus is a flat bonus on top of the modifier, such as from the Alert
+	// feat or Jack of All Trades.
+	Bonus int
+	// Advantage rolls initiative with advantage, such as from Feral
+	// Instinct.
+	Advantage bool
+	// Swap lets the creature swap initiative with a willing ally after
+	// rolling, as with the 2024 Alert feat.
+	Swap bool
+}
+
+// Total returns the total added to an initiative roll.
+func (i Initiative) Total() int {
+	return i.Modifier + i.Bonus
+}
+
+// Range returns the lowest and highest initiative the creature can roll.
+func (i Initiative) Range() (int, int) {
+	return 1 + i.Total(), 20 + i.Total()
+}
+
+// Roll rolls initiative, taking the higher of two d20s with advantage or
+// the lower with disadvantage. Advantage and disadvantage cancel out.
+func (i Initiative) Roll(disadvantage bool) int {
+	roll := rollD20()
+	switch {
+	case i.Advantage && !disadvantage:
+		roll = max(roll, rollD20())
+	case disadvantage && !i.Advantage:
+		roll = min(roll, rollD20())
+	}
+	return roll + i.Total()
+}
+
+// String describes the initiative's bonuses, such as "+3, advantage".
+func (i Initiative) String() string {
+	parts := []string{fmt.Sprintf("%+d", i.Total())}
+	if i.Advantage {
+		parts = append(parts, "advantage")
+	}
+	if i.Swap {
+		parts = append(parts, "can swap")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func rollD20() int {
+	return rand.IntN(20) + 1
+}
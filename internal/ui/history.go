@@ -0,0 +1,307 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/termkit/skeleton"
+)
+
+var _ tea.Model = (*history)(nil)
+
+type historyView int
+
+const (
+	historyList historyView = iota
+	historyDetail
+)
+
+// history lists the archived encounters, most recent first.
+type history struct {
+	skeleton   *skeleton.Skeleton
+	encounters *[]Encounter
+	store      *Store
+	theme      *Theme
+
+	view historyView
+
+	list       list.Model
+	groups     list.Model
+	listKeys   historyListKeyMap
+	detailKeys historyDetailKeyMap
+	help       help.Model
+
+	// the ID of the encounter currently being viewed
+	encounter string
+	// showStats shows the encounter's statistics instead of the initiative
+	// order
+	showStats bool
+	// version is the version of the store the list was built from
+	version int
+}
+
+func newHistory(s *skeleton.Skeleton, encounters *[]Encounter, o options) *history {
+	listKeys := newHistoryListKeyMap(o.keys)
+
+	l := list.New([]list.Item{}, &historyItemDelegate{theme: o.theme}, s.GetContentWidth(), s.GetContentHeight())
+	l.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{listKeys.view}
+	}
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{listKeys.view}
+	}
+	l.KeyMap = newPartyListKeyMap()
+
+	l.SetStatusBarItemName("encounter", "encounters")
+	l.SetShowTitle(false)
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(true)
+	l.DisableQuitKeybindings()
+	o.theme.applyToList(&l)
+
+	h := &history{
+		skeleton:   s,
+		encounters: encounters,
+		store:      o.store,
+		theme:      o.theme,
+
+		view: historyList,
+
+		list:       l,
+		listKeys:   listKeys,
+		detailKeys: newHistoryDetailKeyMap(o.keys),
+		help:       o.theme.newHelp(),
+	}
+	h.syncItems()
+	return h
+}
+
+func (h history) Init() tea.Cmd {
+	return nil
+}
+
+func (h history) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	h.syncItems()
+
+	switch h.view {
+	case historyList:
+		if msg, ok := msg.(tea.MouseMsg); ok {
+			if !scrollList(&h.list, msg) && isClick(msg) {
+				h.list.SetHeight(h.skeleton.GetContentHeight())
+				h.list.SetWidth(h.skeleton.GetContentWidth())
+				if i, _, ok := listItemAt(h.list, &historyItemDelegate{}, msg.Y); ok {
+					h.list.Select(i)
+				}
+			}
+			return h, nil
+		}
+		if msg, ok := msg.(tea.KeyMsg); ok && h.list.FilterState() != list.Filtering {
+			if key.Matches(msg, h.listKeys.view) {
+				if item, ok := h.list.SelectedItem().(historyItem); ok {
+					h.encounter = item.ID
+					h.groups = h.newGroupList(item.Encounter)
+					h.view = historyDetail
+					h.skeleton.UpdatePageTitle("history", "History > "+item.Summary)
+				}
+				return h, nil
+			}
+		}
+
+		var cmd tea.Cmd
+		h.list, cmd = h.list.Update(msg)
+		return h, cmd
+	case historyDetail:
+		if msg, ok := msg.(tea.MouseMsg); ok {
+			scrollList(&h.groups, msg)
+			return h, nil
+		}
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch {
+			case key.Matches(msg, h.detailKeys.back):
+				h.encounter = ""
+				h.view = historyList
+				h.showStats = false
+				h.skeleton.UpdatePageTitle("history", "History")
+				return h, nil
+			case key.Matches(msg, h.detailKeys.stats):
+				h.showStats = !h.showStats
+				return h, nil
+			}
+		}
+
+		var cmd tea.Cmd
+		h.groups, cmd = h.groups.Update(msg)
+		return h, cmd
+	}
+
+	return h, nil
+}
+
+// newGroupList returns a read-only list of the encounter's initiative order
+// as it ended.
+func (h history) newGroupList(e Encounter) list.Model {
+	l := list.New(initiativeGroupItems(e), &initiativeGroupItemDelegate{theme: h.theme}, h.skeleton.GetContentWidth(), h.skeleton.GetContentHeight())
+	l.KeyMap = newListKeyMap()
+	l.SetShowTitle(false)
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(false)
+	l.SetFilteringEnabled(false)
+	l.DisableQuitKeybindings()
+	h.theme.applyToList(&l)
+	return l
+}
+
+func (h history) View() string {
+	h.syncItems()
+
+	switch h.view {
+	case historyList:
+		h.list.SetHeight(h.skeleton.GetContentHeight())
+		h.list.SetWidth(h.skeleton.GetContentWidth())
+		return h.list.View()
+	case historyDetail:
+		e, ok := h.find(h.encounter)
+		if !ok {
+			return ""
+		}
+
+		helpStyle := lipgloss.NewStyle().Padding(0, 1)
+		h.help.Width = h.skeleton.GetContentWidth()
+		helpView := helpStyle.Render(h.help.View(h.detailKeys))
+
+		headerStyle := lipgloss.NewStyle().
+			Bold(true).
+			Foreground(h.theme.title)
+		subtleStyle := lipgloss.NewStyle().Foreground(h.theme.subtle)
+		header := lipgloss.JoinVertical(lipgloss.Left,
+			headerStyle.Render(fmt.Sprintf("Encounter: %s", e.Summary)),
+			subtleStyle.MarginBottom(1).Render(fmt.Sprintf("%s · %s · ended in round %d",
+				e.StartedAt.Format("Mon 2 Jan 2006 15:04"), formatDuration(e.Duration(e.EndedAt)), e.Round)),
+		)
+
+		if waiting := waitingView(e, h.theme); waiting != "" && !h.showStats {
+			helpView = lipgloss.JoinVertical(lipgloss.Left, waiting, helpView)
+		}
+		height := h.skeleton.GetContentHeight() - lipgloss.Height(header) - lipgloss.Height(helpView)
+		if notes := notesView(e, h.groups.SelectedItem(), h.theme, h.skeleton.GetContentWidth(), height/3); notes != "" && !h.showStats {
+			helpView = lipgloss.JoinVertical(lipgloss.Left, notes, helpView)
+			height -= lipgloss.Height(notes)
+		}
+		if h.showStats {
+			return lipgloss.JoinVertical(lipgloss.Left, header, statsView(e, h.theme, height), helpView)
+		}
+
+		h.groups.SetHeight(height)
+		h.groups.SetWidth(h.skeleton.GetContentWidth())
+
+		return lipgloss.JoinVertical(lipgloss.Left, header, h.groups.View(), helpView)
+	}
+
+	return ""
+}
+
+// syncItems rebuilds the list when encounters were archived, or the history
+// was reloaded from the data file, since it was last built.
+func (h *history) syncItems() {
+	if h.encounters == nil || (len(*h.encounters) == len(h.list.Items()) && h.version == h.store.version) {
+		return
+	}
+	h.version = h.store.version
+
+	items := []list.Item{}
+	for _, e := range slices.Backward(*h.encounters) {
+		items = append(items, historyItem{e})
+	}
+	h.list.SetItems(items)
+}
+
+func (h history) find(id string) (Encounter, bool) {
+	if h.encounters == nil {
+		return Encounter{}, false
+	}
+	i := slices.IndexFunc(*h.encounters, func(e Encounter) bool { return e.ID == id })
+	if i < 0 {
+		return Encounter{}, false
+	}
+	return (*h.encounters)[i], true
+}
+
+type historyListKeyMap struct {
+	view key.Binding
+}
+
+func newHistoryListKeyMap(keys KeyBindings) historyListKeyMap {
+	return historyListKeyMap{
+		view: keys.get("history.view"),
+	}
+}
+
+type historyDetailKeyMap struct {
+	stats key.Binding
+	back  key.Binding
+}
+
+func newHistoryDetailKeyMap(keys KeyBindings) historyDetailKeyMap {
+	return historyDetailKeyMap{
+		stats: keys.get("history.stats"),
+		back:  keys.get("history.back"),
+	}
+}
+
+func (k historyDetailKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.stats, k.back}
+}
+
+func (k historyDetailKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.stats, k.back},
+	}
+}
+
+// ------- historyItem
+var _ list.Item = (*historyItem)(nil)
+
+type historyItem struct {
+	Encounter
+}
+
+// FilterValue allows filtering by date, e.g. "2025-06" or "June", and by
+// summary.
+func (h historyItem) FilterValue() string {
+	return h.StartedAt.Format("2006-01-02 Monday 2 January 2006") + " " + h.Summary
+}
+
+// -------- historyItemDelegate
+type historyItemDelegate struct {
+	theme *Theme
+}
+
+func (d historyItemDelegate) Height() int                                { return 1 }
+func (d historyItemDelegate) Spacing() int                               { return 0 }
+func (d *historyItemDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil }
+
+func (d historyItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(historyItem)
+	if !ok {
+		return
+	}
+
+	date := lipgloss.NewStyle().Foreground(d.theme.subtle).Render(i.StartedAt.Format("2006-01-02 15:04"))
+	str := fmt.Sprintf("%s  %s", date, i.Summary)
+
+	fn := lipgloss.NewStyle().PaddingLeft(4).Render
+	if index == m.Index() {
+		fn = func(s ...string) string {
+			return lipgloss.NewStyle().PaddingLeft(2).Foreground(d.theme.selected).Render("> " + strings.Join(s, " "))
+		}
+	}
+
+	fmt.Fprint(w, fn(str))
+}
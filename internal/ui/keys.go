@@ -0,0 +1,240 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+
+	"initiative/internal/config"
+)
+
+// Key binding scopes. Actions in the same scope are available at the same
+// time and can't share keys. Actions in the global scope are available
+// everywhere and can't share keys with any other action.
+const (
+	scopeGlobal               = "global"
+	scopeForm                 = "form"
+	scopeEncounterPlaceholder = "encounter placeholder"
+	scopeEncounterDetail      = "encounter detail"
+	scopePartyList            = "party list"
+	scopePartyDetail          = "party detail"
+	scopeHistoryList          = "history list"
+	scopeHistoryDetail        = "history detail"
+	scopePlayer               = "player view"
+	scopePalette              = "command palette"
+)
+
+// keyAction is an action that can be bound to keys in the config file.
+type keyAction struct {
+	name  string
+	scope string
+	keys  []string
+	help  string
+}
+
+var keyActions = []keyAction{
+	{name: "tabs.next", scope: scopeGlobal, keys: []string{"tab"}, help: "next tab"},
+	{name: "tabs.previous", scope: scopeGlobal, keys: []string{"shift+tab"}, help: "previous tab"},
+
+	{name: "form.exit", scope: scopeForm, keys: []string{"esc"}, help: "exit"},
+
+	{name: "encounter.new", scope: scopeEncounterPlaceholder, keys: []string{"n"}, help: "new encounter"},
+	{name: "encounter.resume", scope: scopeEncounterPlaceholder, keys: []string{"r"}, help: "resume encounter"},
+	{name: "encounter.random", scope: scopeEncounterPlaceholder, keys: []string{"g"}, help: "random encounter"},
+
+	{name: "encounter.next-turn", scope: scopeEncounterDetail, keys: []string{" ", "n"}, help: "next turn"},
+	{name: "encounter.previous-turn", scope: scopeEncounterDetail, keys: []string{"p"}, help: "previous turn"},
+	{name: "encounter.move-up", scope: scopeEncounterDetail, keys: []string{"K", "shift+up"}, help: "move up"},
+	{name: "encounter.move-down", scope: scopeEncounterDetail, keys: []string{"J", "shift+down"}, help: "move down"},
+	{name: "encounter.merge", scope: scopeEncounterDetail, keys: []string{"m"}, help: "merge up"},
+	{name: "encounter.split", scope: scopeEncounterDetail, keys: []string{"x"}, help: "split"},
+	{name: "encounter.damage", scope: scopeEncounterDetail, keys: []string{"-"}, help: "damage"},
+	{name: "encounter.heal", scope: scopeEncounterDetail, keys: []string{"+"}, help: "heal"},
+	{name: "encounter.hp", scope: scopeEncounterDetail, keys: []string{"H"}, help: "hit points"},
+	{name: "encounter.condition", scope: scopeEncounterDetail, keys: []string{"c"}, help: "conditions"},
+	{name: "encounter.notes", scope: scopeEncounterDetail, keys: []string{"N"}, help: "notes"},
+	{name: "encounter.stat-block", scope: scopeEncounterDetail, keys: []string{"b"}, help: "stat block"},
+	{name: "encounter.wait", scope: scopeEncounterDetail, keys: []string{"w"}, help: "delay/ready"},
+	{name: "encounter.rejoin", scope: scopeEncounterDetail, keys: []string{"r"}, help: "rejoin"},
+	{name: "encounter.stats", scope: scopeEncounterDetail, keys: []string{"s"}, help: "toggle stats"},
+	{name: "encounter.stop", scope: scopeEncounterDetail, keys: []string{"esc"}, help: "end encounter"},
+	{name: "encounter.palette", scope: scopeEncounterDetail, keys: []string{":"}, help: "command"},
+
+	{name: "party.new", scope: scopePartyList, keys: []string{"n"}, help: "new"},
+	{name: "party.view", scope: scopePartyList, keys: []string{"enter"}, help: "view"},
+	{name: "party.edit", scope: scopePartyList, keys: []string{"e"}, help: "edit"},
+	{name: "party.delete", scope: scopePartyList, keys: []string{"d"}, help: "delete"},
+	{name: "party.palette", scope: scopePartyList, keys: []string{":"}, help: "command"},
+
+	{name: "party.notes", scope: scopePartyDetail, keys: []string{"n"}, help: "edit notes"},
+	{name: "party.back", scope: scopePartyDetail, keys: []string{"esc"}, help: "back"},
+
+	{name: "history.view", scope: scopeHistoryList, keys: []string{"enter"}, help: "view"},
+
+	{name: "history.stats", scope: scopeHistoryDetail, keys: []string{"s"}, help: "toggle stats"},
+	{name: "history.back", scope: scopeHistoryDetail, keys: []string{"esc"}, help: "back"},
+
+	{name: "palette.run", scope: scopePalette, keys: []string{"enter"}, help: "run"},
+	{name: "palette.complete", scope: scopePalette, keys: []string{"right"}, help: "complete"},
+	{name: "palette.next-suggestion", scope: scopePalette, keys: []string{"down"}, help: "next completion"},
+	{name: "palette.previous-suggestion", scope: scopePalette, keys: []string{"up"}, help: "previous completion"},
+	{name: "palette.history-previous", scope: scopePalette, keys: []string{"ctrl+p"}, help: "previous command"},
+	{name: "palette.history-next", scope: scopePalette, keys: []string{"ctrl+n"}, help: "next command"},
+	{name: "palette.close", scope: scopePalette, keys: []string{"esc"}, help: "close"},
+
+	{name: "player.quit", scope: scopePlayer, keys: []string{"q", "ctrl+c"}, help: "quit"},
+}
+
+// listKeys are the keys the lists handle themselves, see newListKeyMap.
+var listKeys = []string{"up", "k", "down", "j", "left", "h", "pgup", "right", "l", "pgdown", "home", "end", "/", "?"}
+
+// formKeys are the keys forms handle themselves besides the characters typed
+// into them, from huh's default key map. Tab is left to the global scope,
+// which switches tabs.
+var formKeys = []string{"enter", "alt+enter", "up", "down", "left", "right", "home", "end", "ctrl+a", "ctrl+d", "ctrl+e", "ctrl+j", "ctrl+k", "ctrl+n", "ctrl+p", "ctrl+u"}
+
+// builtinKey is a set of keys handled by what's shown in a scope rather than
+// by an action, which actions in the scope or the global scope can't be
+// bound to.
+type builtinKey struct {
+	scope string
+	owner string
+	keys  []string
+}
+
+var builtinKeys = []builtinKey{
+	{scope: scopeForm, owner: "forms", keys: formKeys},
+	{scope: scopeEncounterDetail, owner: "the initiative list", keys: listKeys},
+	{scope: scopePartyList, owner: "the party list", keys: listKeys},
+	{scope: scopeHistoryList, owner: "the history list", keys: listKeys},
+	{scope: scopeHistoryDetail, owner: "the initiative list", keys: listKeys},
+	{scope: scopePlayer, owner: "the initiative list", keys: listKeys},
+}
+
+// KeyBindings holds the binding of every action, by action name.
+type KeyBindings struct {
+	bindings map[string]key.Binding
+}
+
+// DefaultKeyBindings returns the bindings used when none are configured.
+func DefaultKeyBindings() KeyBindings {
+	bindings, _ := NewKeyBindings(nil)
+	return bindings
+}
+
+// NewKeyBindings returns the default bindings with overrides applied. It
+// returns an error when overriding an unknown action, or when a key would be
+// bound to two actions that are available at the same time.
+func NewKeyBindings(overrides map[string]config.KeyBinding) (KeyBindings, error) {
+	for name := range overrides {
+		if !slices.ContainsFunc(keyActions, func(a keyAction) bool { return a.name == name }) {
+			return KeyBindings{}, fmt.Errorf("unknown key binding action %q", name)
+		}
+	}
+
+	actions := make([]keyAction, len(keyActions))
+	copy(actions, keyActions)
+	for i, action := range actions {
+		override, ok := overrides[action.name]
+		if !ok {
+			continue
+		}
+		if len(override.Keys) > 0 {
+			actions[i].keys = override.Keys
+		}
+		if override.Help != "" {
+			actions[i].help = override.Help
+		}
+	}
+
+	if err := checkKeyConflicts(actions); err != nil {
+		return KeyBindings{}, err
+	}
+
+	k := KeyBindings{bindings: map[string]key.Binding{}}
+	for _, action := range actions {
+		k.bindings[action.name] = key.NewBinding(
+			key.WithKeys(action.keys...),
+			key.WithHelp(keyHelp(action.keys[0]), action.help),
+		)
+	}
+	return k, nil
+}
+
+func checkKeyConflicts(actions []keyAction) error {
+	// the action each key is bound to, by scope
+	bound := map[string]map[string]string{}
+
+	for _, action := range actions {
+		for _, k := range action.keys {
+			for _, builtin := range builtinKeys {
+				if builtin.scope != action.scope && action.scope != scopeGlobal {
+					continue
+				}
+				if builtin.scope == scopeForm && len([]rune(k)) == 1 {
+					return fmt.Errorf("key %q for %s would be typed into forms", keyHelp(k), action.name)
+				}
+				if slices.Contains(builtin.keys, k) {
+					return fmt.Errorf("key %q for %s is already used by %s", keyHelp(k), action.name, builtin.owner)
+				}
+			}
+			for scope, keys := range bound {
+				if scope != action.scope && scope != scopeGlobal && action.scope != scopeGlobal {
+					continue
+				}
+				if other, ok := keys[k]; ok && other != action.name {
+					return fmt.Errorf("key %q for %s is already bound to %s", keyHelp(k), action.name, other)
+				}
+			}
+			if bound[action.scope] == nil {
+				bound[action.scope] = map[string]string{}
+			}
+			bound[action.scope][k] = action.name
+		}
+	}
+
+	return nil
+}
+
+// newListKeyMap returns the key map of the lists, bound to listKeys alone so
+// that the letters bubbles also pages with are left to actions.
+func newListKeyMap() list.KeyMap {
+	keyMap := list.DefaultKeyMap()
+	keyMap.PrevPage = key.NewBinding(
+		key.WithKeys("left", "h", "pgup"),
+		key.WithHelp("←/h/pgup", "prev page"),
+	)
+	keyMap.NextPage = key.NewBinding(
+		key.WithKeys("right", "l", "pgdown"),
+		key.WithHelp("→/l/pgdn", "next page"),
+	)
+	keyMap.GoToStart = key.NewBinding(
+		key.WithKeys("home"),
+		key.WithHelp("home", "go to start"),
+	)
+	keyMap.GoToEnd = key.NewBinding(
+		key.WithKeys("end"),
+		key.WithHelp("end", "go to end"),
+	)
+	return keyMap
+}
+
+// keyHelp returns how k is shown in the help.
+func keyHelp(k string) string {
+	if k == " " {
+		return "space"
+	}
+	return strings.TrimSpace(k)
+}
+
+// get returns a copy of the binding of the named action.
+func (k KeyBindings) get(name string) key.Binding {
+	binding, ok := k.bindings[name]
+	if !ok {
+		panic(fmt.Sprintf("no key binding for action %q", name))
+	}
+	return binding
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"initiative/internal/config"
+)
+
+func TestNewKeyBindings(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]config.KeyBinding
+		wantErr   string
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:      "unknown action",
+			overrides: map[string]config.KeyBinding{"encounter.explode": {Keys: []string{"e"}}},
+			wantErr:   `unknown key binding action "encounter.explode"`,
+		},
+		{
+			name:      "same scope",
+			overrides: map[string]config.KeyBinding{"encounter.damage": {Keys: []string{"p"}}},
+			wantErr:   `key "p" for encounter.damage is already bound to encounter.previous-turn`,
+		},
+		{
+			name:      "other scope",
+			overrides: map[string]config.KeyBinding{"encounter.damage": {Keys: []string{"e"}}},
+		},
+		{
+			name:      "list key",
+			overrides: map[string]config.KeyBinding{"encounter.damage": {Keys: []string{"j"}}},
+			wantErr:   `key "j" for encounter.damage is already used by the initiative list`,
+		},
+		{
+			name:      "form key",
+			overrides: map[string]config.KeyBinding{"form.exit": {Keys: []string{"ctrl+e"}}},
+			wantErr:   `key "ctrl+e" for form.exit is already used by forms`,
+		},
+		{
+			name:      "typed into forms",
+			overrides: map[string]config.KeyBinding{"tabs.next": {Keys: []string{"]"}}},
+			wantErr:   `key "]" for tabs.next would be typed into forms`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyBindings(tt.overrides)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewKeyBindings() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewKeyBindings() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
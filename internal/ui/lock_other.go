@@ -0,0 +1,8 @@
+//go:build !unix
+
+package ui
+
+// lockFile doesn't lock anything on platforms without advisory file locks.
+func lockFile(path string) (unlock func(), err error) {
+	return func() {}, nil
+}
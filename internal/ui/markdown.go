@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	markdownBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	markdownNumber  = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	markdownInline  = regexp.MustCompile("\\*\\*(.+?)\\*\\*|__(.+?)__|\\*(.+?)\\*|_(.+?)_|`(.+?)`")
+)
+
+// renderMarkdown renders notes with basic Markdown styling: headings, lists,
+// quotes, bold, italics and code, wrapped to width. Anything else is shown
+// as written.
+func renderMarkdown(text string, theme *Theme, width int) string {
+	textStyle := lipgloss.NewStyle().Foreground(theme.text)
+	headingStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.title)
+	quoteStyle := lipgloss.NewStyle().Italic(true).Foreground(theme.subtle)
+	wrap := func(prefix, line string, style lipgloss.Style) string {
+		indent := strings.Repeat(" ", lipgloss.Width(prefix))
+		wrapped := lipgloss.NewStyle().Width(max(width-len(indent), 1)).Render(renderMarkdownInline(line, style, theme))
+		return prefix + strings.ReplaceAll(wrapped, "\n", "\n"+indent)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		switch {
+		case markdownHeading.MatchString(line):
+			match := markdownHeading.FindStringSubmatch(line)
+			lines = append(lines, wrap("", match[2], headingStyle))
+		case markdownBullet.MatchString(line):
+			match := markdownBullet.FindStringSubmatch(line)
+			lines = append(lines, wrap(match[1]+"• ", match[2], textStyle))
+		case markdownNumber.MatchString(line):
+			match := markdownNumber.FindStringSubmatch(line)
+			lines = append(lines, wrap(match[1]+match[2]+" ", match[3], textStyle))
+		case strings.HasPrefix(line, ">"):
+			lines = append(lines, wrap("│ ", strings.TrimSpace(strings.TrimPrefix(line, ">")), quoteStyle))
+		case strings.TrimSpace(line) == "":
+			lines = append(lines, "")
+		default:
+			lines = append(lines, wrap("", line, textStyle))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderMarkdownInline styles the bold, italic and code spans of a line,
+// rendering the rest with style.
+func renderMarkdownInline(line string, style lipgloss.Style, theme *Theme) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range markdownInline.FindAllStringSubmatchIndex(line, -1) {
+		b.WriteString(style.Render(line[last:match[0]]))
+		switch {
+		case match[2] >= 0:
+			b.WriteString(style.Bold(true).Render(line[match[2]:match[3]]))
+		case match[4] >= 0:
+			b.WriteString(style.Bold(true).Render(line[match[4]:match[5]]))
+		case match[6] >= 0:
+			b.WriteString(style.Italic(true).Render(line[match[6]:match[7]]))
+		case match[8] >= 0:
+			b.WriteString(style.Italic(true).Render(line[match[8]:match[9]]))
+		case match[10] >= 0:
+			b.WriteString(style.Foreground(theme.initiative).Render(line[match[10]:match[11]]))
+		}
+		last = match[1]
+	}
+	b.WriteString(style.Render(line[last:]))
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// migration upgrades the contents of a data file by one version.
+type migration func(data map[string]any) error
+
+// migrations upgrade data files step by step, migrations[i] upgrading a
+// file from version i+1 to version i+2. Files written before the data file
+// had a version are version 1.
+var migrations = []migration{
+	recordEncounterSystems,
+}
+
+// dataVersion is the version of the data files this version of the tracker
+// writes.
+var dataVersion = len(migrations) + 1
+
+// migrate upgrades the contents of the data file or journal at path to the
+// current version in memory, returning the version the file was written
+// with. It refuses files written by a newer version of the tracker, which it
+// can't read without losing data.
+func migrate(path string, data []byte) ([]byte, int, error) {
+	var header struct {
+		Version int `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	version := max(header.Version, 1)
+	if version > dataVersion {
+		return nil, 0, fmt.Errorf("%s was written by a newer version of initiative (data version %d, this version reads up to %d): upgrade initiative to open it", path, version, dataVersion)
+	}
+	if version == dataVersion {
+		return data, version, nil
+	}
+
+	contents := map[string]any{}
+	if err := yaml.Unmarshal(data, &contents); err != nil {
+		return nil, 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for i := version; i < dataVersion; i++ {
+		if err := migrations[i-1](contents); err != nil {
+			return nil, 0, fmt.Errorf("upgrading %s to data version %d: %w", path, i+1, err)
+		}
+	}
+	contents["version"] = dataVersion
+
+	migrated, err := yaml.Marshal(contents)
+	if err != nil {
+		return nil, 0, err
+	}
+	return migrated, version, nil
+}
+
+// backup copies a data file written by an older version of the tracker
+// before it's replaced with the current layout. Backups are named after the
+// file's version and the time, and are never overwritten.
+func (s *Store) backup(c dataContents) error {
+	backup := fmt.Sprintf("%s.v%d.%s.bak", s.path, c.version, time.Now().Format("20060102-150405"))
+	f, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("backing up %s: %w", s.path, err)
+	}
+	if _, err := f.Write(c.data); err != nil {
+		f.Close()
+		return fmt.Errorf("backing up %s: %w", s.path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("backing up %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// migrateJournal upgrades the contents of the journal at path to the
+// current version in memory, like migrate. Journals written before they had
+// a version hold the bare encounter, which is moved under the encounter key.
+func migrateJournal(path string, data []byte) ([]byte, error) {
+	contents := map[string]any{}
+	if err := yaml.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if _, ok := contents["version"]; !ok {
+		var err error
+		if data, err = yaml.Marshal(map[string]any{"encounter": contents}); err != nil {
+			return nil, err
+		}
+	}
+	data, _, err := migrate(path, data)
+	return data, err
+}
+
+// recordEncounterSystems records the game system of archived encounters and
+// the journaled one, which were all played with D&D 5e before game systems
+// could be chosen.
+func recordEncounterSystems(data map[string]any) error {
+	encounters, _ := data["history"].([]any)
+	if journaled, ok := data["encounter"]; ok {
+		encounters = append(encounters, journaled)
+	}
+	for _, encounter := range encounters {
+		encounter, ok := encounter.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid encounter %v", encounter)
+		}
+		if _, ok := encounter["system"]; !ok {
+			encounter["system"] = fifthEdition{}.Name()
+		}
+	}
+	return nil
+}
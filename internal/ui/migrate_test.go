@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dataFileV1 is a data file written before data files had a version, with
+// an encounter from before game systems could be chosen.
+const dataFileV1 = `party:
+  - id: lorem
+    name: Lorem
+history:
+  - id: ambush
+    summary: Goblin ambush
+    started_at: 2024-05-01T19:00:00Z
+    ended_at: 2024-05-01T19:30:00Z
+    round: 3
+    turn: 0
+    groups: []
+`
+
+func TestLoadStoreUpgradesOlderFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.yaml")
+	if err := os.WriteFile(path, []byte(dataFileV1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadStore(path)
+	if err != nil {
+		t.Fatalf("LoadStore() error = %v", err)
+	}
+	if len(s.Party) != 1 || len(s.History) != 1 {
+		t.Fatalf("LoadStore() read %d characters and %d encounters, want 1 and 1", len(s.Party), len(s.History))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file dataFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != dataVersion {
+		t.Errorf("upgraded file has version %d, want %d", file.Version, dataVersion)
+	}
+	if got, want := file.History[0].System, (fifthEdition{}).Name(); got != want {
+		t.Errorf("upgraded encounter has system %q, want %q", got, want)
+	}
+
+	backups, err := filepath.Glob(path + ".v1.*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("found backups %v, want one", backups)
+	}
+	backup, err := os.ReadFile(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != dataFileV1 {
+		t.Errorf("backup = %q, want the original file %q", backup, dataFileV1)
+	}
+}
+
+func TestLoadStoreRefusesNewerFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.yaml")
+	data := []byte("version: 99\nparty: []\nhistory: []\n")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadStore(path); err == nil || !strings.Contains(err.Error(), "newer version") {
+		t.Fatalf("LoadStore() error = %v, want a newer version error", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("file was changed to %q", got)
+	}
+	if backups, _ := filepath.Glob(path + ".*.bak"); len(backups) != 0 {
+		t.Errorf("found backups %v, want none", backups)
+	}
+}
+
+func TestInterruptedReadsVersionedJournals(t *testing.T) {
+	tests := []struct {
+		name    string
+		journal string
+		wantErr string
+	}{
+		{
+			name:    "before journals had a version",
+			journal: "id: ambush\nsummary: Goblin ambush\nround: 2\nturn: 0\ngroups: []\n",
+		},
+		{
+			name:    "current version",
+			journal: "version: 2\nencounter:\n  id: ambush\n  summary: Goblin ambush\n  round: 2\n  turn: 0\n  groups: []\n  system: 5e\n",
+		},
+		{
+			name:    "newer version",
+			journal: "version: 99\nencounter:\n  id: ambush\n",
+			wantErr: "newer version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Store{path: filepath.Join(t.TempDir(), "data.yaml")}
+			if err := os.WriteFile(s.journalPath(), []byte(tt.journal), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			e, ok, err := s.Interrupted()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Interrupted() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !ok {
+				t.Fatalf("Interrupted() = %v, %v, want the journaled encounter", ok, err)
+			}
+			if e.ID != "ambush" || e.Round != 2 {
+				t.Errorf("Interrupted() = encounter %q in round %d, want ambush in round 2", e.ID, e.Round)
+			}
+			if got, want := e.Rules().Name(), (fifthEdition{}).Name(); got != want {
+				t.Errorf("Interrupted() encounter has system %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestJournalWritesVersion(t *testing.T) {
+	s := &Store{path: filepath.Join(t.TempDir(), "data.yaml")}
+	if err := s.Journal(Encounter{ID: "ambush"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(s.journalPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file journalFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != dataVersion || file.Encounter.ID != "ambush" {
+		t.Errorf("journal has version %d and encounter %q, want %d and ambush", file.Version, file.Encounter.ID, dataVersion)
+	}
+}
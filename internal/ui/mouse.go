@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// isClick reports whether msg is a press of the left mouse button.
+func isClick(msg tea.MouseMsg) bool {
+	return msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft
+}
+
+// scrollList moves the cursor of l by one item for a turn of the mouse
+// wheel, reporting whether msg was one.
+func scrollList(l *list.Model, msg tea.MouseMsg) bool {
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		l.CursorUp()
+	case tea.MouseButtonWheelDown:
+		l.CursorDown()
+	default:
+		return false
+	}
+	return true
+}
+
+// listItemAt returns the index of the item of l drawn by d at row y of the
+// list, counted from its top, and the line of the item at that row.
+func listItemAt(l list.Model, d list.ItemDelegate, y int) (index, line int, ok bool) {
+	// rows above the items: the title bar, which is drawn empty when only
+	// the filter is shown, and the status bar
+	if l.ShowTitle() || (l.ShowFilter() && l.FilteringEnabled()) {
+		if l.ShowTitle() || l.FilterState() == list.Filtering {
+			y -= lipgloss.Height(l.Styles.TitleBar.Render(" "))
+		} else {
+			y--
+		}
+	}
+	if l.ShowStatusBar() {
+		y -= lipgloss.Height(l.Styles.StatusBar.Render(" "))
+	}
+
+	step := d.Height() + d.Spacing()
+	if y < 0 || y%step >= d.Height() {
+		return 0, 0, false
+	}
+	start, end := l.Paginator.GetSliceBounds(len(l.VisibleItems()))
+	index = start + y/step
+	if index >= end {
+		return 0, 0, false
+	}
+	return index, y % step, true
+}
@@ -0,0 +1,324 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+)
+
+// maxSuggestions is how many completions the palette shows at once.
+const maxSuggestions = 5
+
+// paletteCommand is a command that can be run from the command palette.
+type paletteCommand struct {
+	name  string
+	usage string
+	// args is what each argument names, for completing it
+	args []commandArg
+}
+
+// scriptPaletteCommands returns the commands of the script language, which
+// the encounter page's palette runs.
+func scriptPaletteCommands() []paletteCommand {
+	commands := []paletteCommand{}
+	for _, c := range scriptCommands {
+		commands = append(commands, paletteCommand{name: c.name, usage: c.usage, args: c.args})
+	}
+	return commands
+}
+
+// palette is a command line opened over a page, completing command names
+// and the names of creatures, characters and conditions by fuzzy matching,
+// and recalling the commands run before.
+type palette struct {
+	input    textinput.Model
+	keys     paletteKeyMap
+	help     help.Model
+	theme    *Theme
+	commands []paletteCommand
+
+	// complete returns the names an argument can be completed with, set by
+	// the page before every update
+	complete func(commandArg) []string
+
+	active bool
+	// history holds the commands run, oldest first
+	history []string
+	// recalled is the index of the history entry being shown, or
+	// len(history) when none is
+	recalled int
+	// draft is what was typed before recalling the history
+	draft string
+
+	// suggestions are the completions of the word being typed, best first
+	suggestions []string
+	// selected is the index of the suggestion accepted by the complete key
+	selected int
+
+	// err is the error from running the last command
+	err error
+}
+
+func newPalette(commands []paletteCommand, keys KeyBindings, theme *Theme) *palette {
+	input := textinput.New()
+	input.Prompt = ":"
+	input.PromptStyle = lipgloss.NewStyle().Foreground(theme.initiative)
+	input.TextStyle = lipgloss.NewStyle().Foreground(theme.text)
+
+	return &palette{
+		input:    input,
+		keys:     newPaletteKeyMap(keys),
+		help:     theme.newHelp(),
+		theme:    theme,
+		commands: commands,
+		complete: func(commandArg) []string { return nil },
+	}
+}
+
+// open opens the palette with an empty command line.
+func (p *palette) open() tea.Cmd {
+	p.active = true
+	p.err = nil
+	p.recalled = len(p.history)
+	p.input.SetValue("")
+	p.suggest()
+	return p.input.Focus()
+}
+
+// close closes the palette.
+func (p *palette) close() {
+	p.active = false
+	p.input.Blur()
+}
+
+// Update handles a key pressed while the palette is open, returning the
+// command line when the run key is pressed. The palette stays open so that
+// a command that fails can be corrected; the page closes it otherwise.
+func (p *palette) Update(msg tea.KeyMsg) (string, tea.Cmd) {
+	switch {
+	case key.Matches(msg, p.keys.close):
+		p.close()
+		return "", nil
+	case key.Matches(msg, p.keys.run):
+		line := strings.TrimSpace(p.input.Value())
+		if line == "" {
+			p.close()
+			return "", nil
+		}
+		if len(p.history) == 0 || p.history[len(p.history)-1] != line {
+			p.history = append(p.history, line)
+		}
+		p.recalled = len(p.history)
+		p.err = nil
+		return line, nil
+	case key.Matches(msg, p.keys.nextSuggestion) && len(p.suggestions) > 0:
+		p.selected = (p.selected + 1) % len(p.suggestions)
+		return "", nil
+	case key.Matches(msg, p.keys.previousSuggestion) && len(p.suggestions) > 0:
+		p.selected = (p.selected + len(p.suggestions) - 1) % len(p.suggestions)
+		return "", nil
+	case key.Matches(msg, p.keys.complete) && len(p.suggestions) > 0 && p.input.Position() == len(p.input.Value()):
+		_, _, start := splitPartial(p.input.Value())
+		p.input.SetValue(p.input.Value()[:start] + quoteWord(p.suggestions[p.selected]) + " ")
+		p.input.CursorEnd()
+		p.suggest()
+		return "", nil
+	case key.Matches(msg, p.keys.historyPrevious):
+		p.recall(-1)
+		return "", nil
+	case key.Matches(msg, p.keys.historyNext):
+		p.recall(1)
+		return "", nil
+	}
+
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	p.suggest()
+	return "", cmd
+}
+
+// recall replaces the command line with an older or newer command from the
+// history, returning to what was typed past the newest one.
+func (p *palette) recall(delta int) {
+	recalled := min(max(p.recalled+delta, 0), len(p.history))
+	if recalled == p.recalled {
+		return
+	}
+	if p.recalled == len(p.history) {
+		p.draft = p.input.Value()
+	}
+	p.recalled = recalled
+
+	if recalled == len(p.history) {
+		p.input.SetValue(p.draft)
+	} else {
+		p.input.SetValue(p.history[recalled])
+	}
+	p.input.CursorEnd()
+	p.suggest()
+}
+
+// suggest completes the word being typed: a command name first, then the
+// names its arguments refer to.
+func (p *palette) suggest() {
+	words, current, _ := splitPartial(p.input.Value())
+
+	candidates := []string{}
+	switch {
+	case len(words) == 0:
+		for _, c := range p.commands {
+			candidates = append(candidates, c.name)
+		}
+	default:
+		i := slices.IndexFunc(p.commands, func(c paletteCommand) bool { return strings.EqualFold(c.name, words[0]) })
+		if i < 0 {
+			break
+		}
+		arg := argText
+		if n := len(words) - 1; n < len(p.commands[i].args) {
+			arg = p.commands[i].args[n]
+		}
+		// damage and healing name their source after "from"
+		if strings.EqualFold(words[len(words)-1], "from") {
+			arg = argCreature
+		}
+		if arg != argText {
+			candidates = p.complete(arg)
+		}
+	}
+
+	p.selected = 0
+	p.suggestions = nil
+	if current == "" {
+		p.suggestions = candidates
+	} else {
+		for _, match := range fuzzy.Find(current, candidates) {
+			p.suggestions = append(p.suggestions, match.Str)
+		}
+	}
+	if len(p.suggestions) > maxSuggestions {
+		p.suggestions = p.suggestions[:maxSuggestions]
+	}
+}
+
+// splitPartial splits a command line being typed into its complete words
+// and the word being typed, which starts at byte offset start. Words in
+// double quotes are kept together.
+func splitPartial(line string) (words []string, current string, start int) {
+	var word strings.Builder
+	inWord, quoted := false, false
+	for i, r := range line {
+		switch {
+		case r == '"' && (!inWord || quoted):
+			if quoted {
+				quoted = false
+				continue
+			}
+			inWord, quoted, start = true, true, i
+		case r == ' ' && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			if !inWord {
+				inWord, start = true, i
+			}
+			word.WriteRune(r)
+		}
+	}
+	if !inWord {
+		start = len(line)
+	}
+	return words, word.String(), start
+}
+
+// quoteWord quotes a word containing spaces, such as "Goblin 2".
+func quoteWord(word string) string {
+	if strings.Contains(word, " ") {
+		return `"` + word + `"`
+	}
+	return word
+}
+
+// usage returns the usage of the command being typed.
+func (p *palette) usage() string {
+	words, current, _ := splitPartial(p.input.Value())
+	name := current
+	if len(words) > 0 {
+		name = words[0]
+	}
+	i := slices.IndexFunc(p.commands, func(c paletteCommand) bool { return strings.EqualFold(c.name, name) })
+	if i < 0 {
+		return ""
+	}
+	return p.commands[i].usage
+}
+
+// View renders the command line with its completions, to be shown at the
+// bottom of the page in place of its help.
+func (p *palette) View(width int) string {
+	subtleStyle := lipgloss.NewStyle().Foreground(p.theme.subtle)
+	p.input.Width = max(width-3, 1)
+
+	lines := []string{}
+	for i, suggestion := range p.suggestions {
+		if i == p.selected {
+			lines = append(lines, lipgloss.NewStyle().Foreground(p.theme.selected).Render("▸ "+suggestion))
+			continue
+		}
+		lines = append(lines, subtleStyle.Render("  "+suggestion))
+	}
+	if usage := p.usage(); usage != "" {
+		lines = append(lines, subtleStyle.Render(usage))
+	}
+	lines = append(lines, p.input.View())
+	if p.err != nil {
+		lines = append(lines, p.theme.renderError(p.err))
+	}
+	p.help.Width = width
+	lines = append(lines, p.help.View(p.keys))
+
+	return lipgloss.NewStyle().Padding(0, 1).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+type paletteKeyMap struct {
+	run                key.Binding
+	complete           key.Binding
+	nextSuggestion     key.Binding
+	previousSuggestion key.Binding
+	historyPrevious    key.Binding
+	historyNext        key.Binding
+	close              key.Binding
+}
+
+func newPaletteKeyMap(keys KeyBindings) paletteKeyMap {
+	return paletteKeyMap{
+		run:                keys.get("palette.run"),
+		complete:           keys.get("palette.complete"),
+		nextSuggestion:     keys.get("palette.next-suggestion"),
+		previousSuggestion: keys.get("palette.previous-suggestion"),
+		historyPrevious:    keys.get("palette.history-previous"),
+		historyNext:        keys.get("palette.history-next"),
+		close:              keys.get("palette.close"),
+	}
+}
+
+func (k paletteKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.run, k.complete, k.nextSuggestion, k.historyPrevious, k.close}
+}
+
+func (k paletteKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.run, k.close},
+		{k.complete, k.nextSuggestion, k.previousSuggestion},
+		{k.historyPrevious, k.historyNext},
+	}
+}
@@ -1,8 +1,11 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/help"
@@ -11,6 +14,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
 	"github.com/termkit/skeleton"
 )
 
@@ -22,48 +26,57 @@ const (
 	partyList partyView = iota
 	partyDetail
 	partyForm
+	partyNotesForm
+	partyConflictForm
 )
 
 type party struct {
 	skeleton *skeleton.Skeleton
 	party    *map[string]Character
+	store    *Store
 
 	view partyView
 
+	// system is the default game system, which names the initiative
+	// modifier
+	system GameSystem
+
+	theme      *Theme
+	formKeys   *huh.KeyMap
 	list       list.Model
 	listKeys   additionalGameListKeyMap
 	form       *huh.Form
 	detailKeys partyDetailKeyMap
 	help       help.Model
+	palette    *palette
 
 	// the uuid of the character currently being viewed or edited
 	character string
+	// version is the version of the store the list was built from
+	version int
+	// conflictView is the view to return to once a conflict with changes
+	// made to the data file on disk is resolved
+	conflictView partyView
+
+	// err is the error from saving the last change to the party
+	err error
 }
 
-func newParty(s *skeleton.Skeleton, p *map[string]Character) *party {
-	items := []list.Item{}
+func newParty(s *skeleton.Skeleton, p *map[string]Character, o options) *party {
+	items := partyItems(p)
 
-	if p != nil {
-		for uuid, character := range *p {
-			items = append(
-				items,
-				characterItem{uuid: uuid, Character: character},
-			)
-		}
-	}
-
-	characterItemKeyMap := newCharacterItemKeyMap()
+	characterItemKeyMap := newCharacterItemKeyMap(o.keys)
 	if len(items) == 0 {
 		characterItemKeyMap.view.SetEnabled(false)
 		characterItemKeyMap.edit.SetEnabled(false)
 		characterItemKeyMap.delete.SetEnabled(false)
 	}
 
-	l := list.New(items, &characterItemDelegate{keys: characterItemKeyMap}, s.GetContentWidth(), s.GetContentHeight())
+	l := list.New(items, &characterItemDelegate{keys: characterItemKeyMap, theme: o.theme}, s.GetContentWidth(), s.GetContentHeight())
 
-	additionalPartyListKeyMap := newAdditionalPartyListKeyMap()
+	additionalPartyListKeyMap := newAdditionalPartyListKeyMap(o.keys)
 	l.AdditionalFullHelpKeys = func() []key.Binding {
-		return []key.Binding{additionalPartyListKeyMap.newCharacter}
+		return []key.Binding{additionalPartyListKeyMap.newCharacter, additionalPartyListKeyMap.palette}
 	}
 	l.AdditionalShortHelpKeys = func() []key.Binding {
 		return []key.Binding{additionalPartyListKeyMap.newCharacter}
@@ -75,17 +88,24 @@ func newParty(s *skeleton.Skeleton, p *map[string]Character) *party {
 	l.SetShowStatusBar(false)
 	l.SetShowHelp(true)
 	l.DisableQuitKeybindings()
+	o.theme.applyToList(&l)
 
 	return &party{
 		skeleton: s,
 		party:    p,
+		store:    o.store,
 
-		view: partyList,
+		view:    partyList,
+		system:  o.system,
+		version: o.store.version,
 
+		theme:      o.theme,
+		formKeys:   customFormKeyMap(o.keys),
 		list:       l,
 		listKeys:   additionalPartyListKeyMap,
-		detailKeys: newPartyDetailKeyMap(),
-		help:       help.New(),
+		detailKeys: newPartyDetailKeyMap(o.keys),
+		help:       o.theme.newHelp(),
+		palette:    newPalette(partyCommands, o.keys, o.theme),
 	}
 }
 
@@ -94,8 +114,21 @@ func (p party) Init() tea.Cmd {
 }
 
 func (p party) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	p.syncItems()
+
 	switch msg := msg.(type) {
+	case tea.MouseMsg:
+		if p.view == partyList {
+			p.updateMouse(msg)
+			return p, nil
+		}
 	case tea.KeyMsg:
+		if p.view == partyList && p.palette.active {
+			return p, p.updatePalette(msg)
+		}
+		if key.Matches(msg, p.listKeys.palette) && p.view == partyList && p.list.FilterState() != list.Filtering {
+			return p, p.palette.open()
+		}
 		if key.Matches(msg, p.listKeys.newCharacter) && p.listKeys.newCharacter.Enabled() && p.view == partyList {
 			return p, tea.Cmd(func() tea.Msg {
 				return editCharacterMsg{uuid: ""}
@@ -118,20 +151,83 @@ func (p party) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case editCharacterMsg:
 		{
-			var name string
+			var name, level, armorClass string
+			var initiative Initiative
 			if msg.uuid != "" && p.party != nil {
 				if character, exists := (*p.party)[msg.uuid]; exists {
 					name = character.Name()
+					initiative = character.Initiative()
+					if character.Level() > 0 {
+						level = strconv.Itoa(character.Level())
+					}
+					if character.ArmorClass() > 0 {
+						armorClass = strconv.Itoa(character.ArmorClass())
+					}
+				}
+			}
+			modifier := strconv.Itoa(initiative.Modifier)
+			bonus := strconv.Itoa(initiative.Bonus)
+			validateBonus := func(str string) error {
+				if _, err := parseBonus(str); err != nil {
+					return fmt.Errorf("Must be a whole number like +2 or -1")
 				}
+				return nil
 			}
+
 			p.form = huh.NewForm(
 				huh.NewGroup(
 					huh.NewInput().
 						Key("name").
 						Title("Name").
 						Value(&name),
+					huh.NewInput().
+						Key("level").
+						Title("Level").
+						Description("For generating random encounters").
+						Value(&level).
+						Validate(func(str string) error {
+							if _, err := parseLevel(str); err != nil {
+								return fmt.Errorf("Level must be a number from 1 to 20")
+							}
+							return nil
+						}),
+					huh.NewInput().
+						Key("armorClass").
+						Title("Armor class").
+						Value(&armorClass).
+						Validate(func(str string) error {
+							if _, err := parseCount(str, 0); err != nil {
+								return fmt.Errorf("Armor class must be a number")
+							}
+							return nil
+						}),
+					huh.NewInput().
+						Key("modifier").
+						Title(p.system.InitiativeName()+" modifier").
+						Value(&modifier).
+						Validate(validateBonus),
+					huh.NewInput().
+						Key("bonus").
+						Title("Initiative bonus").
+						Description("Alert, Jack of All Trades, ...").
+						Value(&bonus).
+						Validate(validateBonus),
+					huh.NewConfirm().
+						Key("advantage").
+						Title("Advantage on initiative?").
+						Description("Feral Instinct, ...").
+						Affirmative("Yes").
+						Negative("No").
+						Value(&initiative.Advantage),
+					huh.NewConfirm().
+						Key("swap").
+						Title("Can swap initiative with an ally?").
+						Description("Alert (2024)").
+						Affirmative("Yes").
+						Negative("No").
+						Value(&initiative.Swap),
 				),
-			)
+			).WithKeyMap(p.formKeys).WithTheme(p.theme.formTheme())
 			p.character = msg.uuid
 			p.view = partyForm
 			return p, p.form.Init()
@@ -150,7 +246,7 @@ func (p party) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					break
 				}
 			}
-			return p, nil
+			return p, p.save()
 		}
 	}
 
@@ -172,6 +268,20 @@ func (p party) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					p.view = partyList
 					p.skeleton.UpdatePageTitle("party", "Party")
 					return p, nil
+				case key.Matches(msg, p.detailKeys.notes):
+					notes := (*p.party)[p.character].Notes()
+					p.form = huh.NewForm(
+						huh.NewGroup(
+							huh.NewText().
+								Key("notes").
+								Title("Notes").
+								Description("Markdown: # headings, - lists, **bold**, *italics*, `code`").
+								Lines(12).
+								Value(&notes),
+						),
+					).WithKeyMap(p.formKeys).WithTheme(p.theme.formTheme())
+					p.view = partyNotesForm
+					return p, p.form.Init()
 				}
 			}
 		}
@@ -182,8 +292,26 @@ func (p party) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				p.form = f
 			}
 
+			if p.form.State == huh.StateAborted {
+				p.view = partyList
+				p.character = ""
+				return p, nil
+			}
+
 			if p.form.State == huh.StateCompleted {
 				name := p.form.GetString("name")
+				// validation already ensures the level, armor class and
+				// bonuses can be parsed
+				level, _ := parseLevel(p.form.GetString("level"))
+				armorClass, _ := parseCount(p.form.GetString("armorClass"), 0)
+				modifier, _ := parseBonus(p.form.GetString("modifier"))
+				bonus, _ := parseBonus(p.form.GetString("bonus"))
+				initiative := Initiative{
+					Modifier:  modifier,
+					Bonus:     bonus,
+					Advantage: p.form.GetBool("advantage"),
+					Swap:      p.form.GetBool("swap"),
+				}
 
 				if p.character != "" {
 					// 1. editing existing character
@@ -191,6 +319,9 @@ func (p party) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						// Update the character in the map
 						character := (*p.party)[p.character]
 						character.name = name
+						character.initiative = initiative
+						character.level = level
+						character.armorClass = armorClass
 						(*p.party)[p.character] = character
 
 						// Find and update the corresponding list item with the updated character
@@ -205,8 +336,8 @@ func (p party) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				} else {
 					// 2. adding new character - generate new UUID
-					character := Character{name: name}
-					uuid := fmt.Sprintf("char_%d", len(*p.party))
+					uuid := uuid.New().String()
+					character := Character{id: uuid, name: name, initiative: initiative, level: level, armorClass: armorClass}
 					if p.party == nil {
 						newParty := make(map[string]Character)
 						p.party = &newParty
@@ -218,6 +349,64 @@ func (p party) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				p.view = partyList
 				p.character = ""
+				return p, p.save()
+			}
+
+			return p, cmd
+		}
+	case partyNotesForm:
+		{
+			form, cmd := p.form.Update(msg)
+			if f, ok := form.(*huh.Form); ok {
+				p.form = f
+			}
+
+			if p.form.State == huh.StateAborted {
+				p.view = partyDetail
+				return p, nil
+			}
+
+			if p.form.State == huh.StateCompleted {
+				if character, exists := (*p.party)[p.character]; exists {
+					character.notes = p.form.GetString("notes")
+					(*p.party)[p.character] = character
+
+					for i, item := range p.list.Items() {
+						if charItem, ok := item.(characterItem); ok && charItem.uuid == p.character {
+							p.list.SetItem(i, characterItem{uuid: p.character, Character: character})
+							break
+						}
+					}
+				}
+
+				p.view = partyDetail
+				return p, p.save()
+			}
+
+			return p, cmd
+		}
+	case partyConflictForm:
+		{
+			form, cmd := p.form.Update(msg)
+			if f, ok := form.(*huh.Form); ok {
+				p.form = f
+			}
+
+			if p.form.State == huh.StateAborted {
+				p.view = p.conflictView
+				return p, nil
+			}
+
+			if p.form.State == huh.StateCompleted {
+				p.err = p.form.Get("resolution").(conflictResolution).resolve(p.store)
+				p.syncItems()
+				p.view = p.conflictView
+				if _, exists := (*p.party)[p.character]; p.view == partyDetail && !exists {
+					p.character = ""
+					p.view = partyList
+					p.skeleton.UpdatePageTitle("party", "Party")
+				}
+				return p, nil
 			}
 
 			return p, cmd
@@ -228,26 +417,61 @@ func (p party) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (p party) View() string {
+	p.syncItems()
+
 	switch p.view {
 	case partyList:
-		p.list.SetHeight(p.skeleton.GetContentHeight())
-		p.list.SetWidth(p.skeleton.GetContentWidth())
+		if footer := p.sizeList(); footer != "" {
+			return lipgloss.JoinVertical(lipgloss.Left, p.list.View(), footer)
+		}
 		return p.list.View()
 	case partyDetail:
-		var characterName string
+		var characterName, notes, statBlock string
+		var initiative Initiative
+		var level, armorClass int
 		if p.party != nil {
 			if character, exists := (*p.party)[p.character]; exists {
 				characterName = character.Name()
+				initiative = character.Initiative()
+				notes = character.Notes()
+				statBlock = character.StatBlock()
+				level = character.Level()
+				armorClass = character.ArmorClass()
 			}
 		}
 
 		// Calculate available height for content
 		helpStyle := lipgloss.NewStyle().PaddingBottom(1)
 		helpView := helpStyle.Render(p.help.View(p.detailKeys))
+		if p.err != nil {
+			helpView = lipgloss.JoinVertical(lipgloss.Left, p.theme.renderError(p.err), helpView)
+		}
 		availHeight := p.skeleton.GetContentHeight() - lipgloss.Height(helpView)
 
 		// Create main content area
-		content := fmt.Sprintf("Viewing character: %s", characterName)
+		stats := CampaignStats(p.store.History)[p.character]
+		summary := levelText(level)
+		if armorClass > 0 {
+			summary += fmt.Sprintf(" · AC %d", armorClass)
+		}
+		content := lipgloss.JoinVertical(lipgloss.Left,
+			fmt.Sprintf("Viewing character: %s", characterName),
+			lipgloss.NewStyle().Foreground(p.theme.subtle).Render(summary+" · "+p.system.InitiativeName()+" "+initiative.String()),
+			"",
+			campaignStatsView(stats, p.theme),
+		)
+		if strings.TrimSpace(statBlock) != "" {
+			content = lipgloss.JoinVertical(lipgloss.Left, content, "",
+				lipgloss.NewStyle().Bold(true).Foreground(p.theme.initiative).Render("Stat block"),
+				renderMarkdown(statBlock, p.theme, p.skeleton.GetContentWidth()),
+			)
+		}
+		if strings.TrimSpace(notes) != "" {
+			content = lipgloss.JoinVertical(lipgloss.Left, content, "",
+				lipgloss.NewStyle().Bold(true).Foreground(p.theme.initiative).Render("Notes"),
+				renderMarkdown(notes, p.theme, p.skeleton.GetContentWidth()),
+			)
+		}
 		contentArea := lipgloss.NewStyle().
 			Height(availHeight).
 			Width(p.skeleton.GetContentWidth()).
@@ -256,7 +480,7 @@ func (p party) View() string {
 			Render(content)
 
 		return lipgloss.JoinVertical(lipgloss.Left, contentArea, helpView)
-	case partyForm:
+	case partyForm, partyNotesForm, partyConflictForm:
 		p.form.WithHeight(p.skeleton.GetContentHeight()).WithWidth(p.skeleton.GetContentWidth())
 		return p.form.View()
 	}
@@ -264,8 +488,188 @@ func (p party) View() string {
 	return ""
 }
 
+// sizeList sizes the list to leave room for what's shown below it, the
+// palette or the last error, which it returns.
+func (p *party) sizeList() string {
+	footer := ""
+	switch {
+	case p.palette.active:
+		footer = p.palette.View(p.skeleton.GetContentWidth())
+	case p.err != nil:
+		footer = p.theme.renderError(p.err)
+	}
+
+	height := p.skeleton.GetContentHeight()
+	if footer != "" {
+		height -= lipgloss.Height(footer)
+	}
+	p.list.SetShowHelp(!p.palette.active)
+	p.list.SetHeight(height)
+	p.list.SetWidth(p.skeleton.GetContentWidth())
+	return footer
+}
+
+// updateMouse selects the character clicked in the list, or moves through
+// the list with the mouse wheel.
+func (p *party) updateMouse(msg tea.MouseMsg) {
+	if scrollList(&p.list, msg) || !isClick(msg) {
+		return
+	}
+
+	// lay the list out as it's drawn, which decides the characters on its
+	// page
+	p.sizeList()
+	if i, _, ok := listItemAt(p.list, &characterItemDelegate{}, msg.Y); ok {
+		p.list.Select(i)
+	}
+}
+
+// save saves the party, asking how to resolve a conflict with changes made
+// to the data file on disk since it was loaded.
+func (p *party) save() tea.Cmd {
+	p.err = p.store.Save()
+	if !errors.Is(p.err, ErrDataChanged) {
+		return nil
+	}
+
+	p.conflictView = p.view
+	p.form = newConflictForm(p.formKeys, p.theme)
+	p.view = partyConflictForm
+	return p.form.Init()
+}
+
+// partyCommands are the commands of the party page's palette.
+var partyCommands = []paletteCommand{
+	{name: "new", usage: "new"},
+	{name: "view", usage: "view <character>", args: []commandArg{argCharacter}},
+	{name: "edit", usage: "edit <character>", args: []commandArg{argCharacter}},
+	{name: "delete", usage: "delete <character>", args: []commandArg{argCharacter}},
+}
+
+// updatePalette passes a key to the command palette, running the command
+// line once it's entered.
+func (p *party) updatePalette(msg tea.KeyMsg) tea.Cmd {
+	p.palette.complete = p.completions
+	line, cmd := p.palette.Update(msg)
+	if line == "" {
+		return cmd
+	}
+
+	cmd, err := p.runCommand(line)
+	if err != nil {
+		p.palette.err = err
+		return nil
+	}
+	p.palette.close()
+	return cmd
+}
+
+// runCommand runs a command line from the palette, doing what the
+// matching key does.
+func (p *party) runCommand(line string) (tea.Cmd, error) {
+	args, err := splitCommand(line)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.ToLower(args[0])
+	if !slices.ContainsFunc(partyCommands, func(c paletteCommand) bool { return c.name == name }) {
+		return nil, fmt.Errorf("unknown command %q, expected one of new, view, edit, delete", args[0])
+	}
+	if name == "new" {
+		return func() tea.Msg { return editCharacterMsg{uuid: ""} }, nil
+	}
+
+	if len(args) != 2 {
+		return nil, fmt.Errorf("usage: %s <character>", name)
+	}
+	id := ""
+	if p.party != nil {
+		for _, character := range sortedCharacters(*p.party) {
+			if strings.EqualFold(character.Name(), args[1]) {
+				id = character.ID()
+			}
+		}
+	}
+	if id == "" {
+		return nil, fmt.Errorf("no character named %q", args[1])
+	}
+
+	switch name {
+	case "view":
+		return func() tea.Msg { return viewCharacterMsg{uuid: id} }, nil
+	case "edit":
+		return func() tea.Msg { return editCharacterMsg{uuid: id} }, nil
+	}
+	return func() tea.Msg { return deleteCharacterMsg{uuid: id} }, nil
+}
+
+// completions returns the names an argument of a command can be completed
+// with in the palette.
+func (p party) completions(arg commandArg) []string {
+	names := []string{}
+	if arg == argCharacter && p.party != nil {
+		for _, character := range sortedCharacters(*p.party) {
+			names = append(names, character.Name())
+		}
+	}
+	return names
+}
+
+// syncItems rebuilds the list when the party was reloaded from the data
+// file since it was last built.
+func (p *party) syncItems() {
+	if p.version == p.store.version {
+		return
+	}
+	p.version = p.store.version
+	p.list.SetItems(partyItems(p.party))
+}
+
+// partyItems returns the party's characters as list items, ordered by name.
+func partyItems(p *map[string]Character) []list.Item {
+	items := []list.Item{}
+	if p != nil {
+		for _, character := range sortedCharacters(*p) {
+			items = append(items, characterItem{uuid: character.ID(), Character: character})
+		}
+	}
+	return items
+}
+
+// levelText describes a character's level.
+func levelText(level int) string {
+	if level == 0 {
+		return "Level unknown"
+	}
+	return fmt.Sprintf("Level %d", level)
+}
+
+// parseLevel parses a character's level, where nothing means it isn't
+// known.
+func parseLevel(str string) (int, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, nil
+	}
+	level, err := strconv.Atoi(str)
+	if err != nil || level < 1 || level > 20 {
+		return 0, fmt.Errorf("invalid level %q", str)
+	}
+	return level, nil
+}
+
+// parseBonus parses a modifier or bonus such as "+2" or "-1", where nothing
+// means no bonus.
+func parseBonus(str string) (int, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(strings.TrimPrefix(str, "+"))
+}
+
 func newPartyListKeyMap() list.KeyMap {
-	keyMap := list.DefaultKeyMap()
+	keyMap := newListKeyMap()
 
 	// Disable GoToStart and GoToEnd
 	keyMap.GoToStart = key.NewBinding(key.WithDisabled())
@@ -286,37 +690,35 @@ func newPartyListKeyMap() list.KeyMap {
 
 type additionalGameListKeyMap struct {
 	newCharacter key.Binding
+	palette      key.Binding
 }
 
-func newAdditionalPartyListKeyMap() additionalGameListKeyMap {
+func newAdditionalPartyListKeyMap(keys KeyBindings) additionalGameListKeyMap {
 	return additionalGameListKeyMap{
-		newCharacter: key.NewBinding(
-			key.WithKeys("n"),
-			key.WithHelp("n", "new"),
-		),
+		newCharacter: keys.get("party.new"),
+		palette:      keys.get("party.palette"),
 	}
 }
 
 type partyDetailKeyMap struct {
-	back key.Binding
+	notes key.Binding
+	back  key.Binding
 }
 
-func newPartyDetailKeyMap() partyDetailKeyMap {
+func newPartyDetailKeyMap(keys KeyBindings) partyDetailKeyMap {
 	return partyDetailKeyMap{
-		back: key.NewBinding(
-			key.WithKeys("esc"),
-			key.WithHelp("esc", "back"),
-		),
+		notes: keys.get("party.notes"),
+		back:  keys.get("party.back"),
 	}
 }
 
 func (k partyDetailKeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.back}
+	return []key.Binding{k.notes, k.back}
 }
 
 func (k partyDetailKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.back},
+		{k.notes, k.back},
 	}
 }
 
@@ -332,7 +734,8 @@ func (c characterItem) FilterValue() string { return c.Name() }
 
 // -------- characterItemDelegate
 type characterItemDelegate struct {
-	keys characterItemKeyMap
+	keys  characterItemKeyMap
+	theme *Theme
 }
 
 type viewCharacterMsg struct {
@@ -398,7 +801,7 @@ func (c characterItemDelegate) Render(w io.Writer, m list.Model, index int, list
 	fn := lipgloss.NewStyle().PaddingLeft(4).Render
 	if index == m.Index() {
 		fn = func(s ...string) string {
-			return lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("170")).Render("> " + strings.Join(s, " "))
+			return lipgloss.NewStyle().PaddingLeft(2).Foreground(c.theme.selected).Render("> " + strings.Join(s, " "))
 		}
 	}
 
@@ -447,19 +850,10 @@ func (d characterItemKeyMap) FullHelp() [][]key.Binding {
 	}
 }
 
-func newCharacterItemKeyMap() characterItemKeyMap {
+func newCharacterItemKeyMap(keys KeyBindings) characterItemKeyMap {
 	return characterItemKeyMap{
-		view: key.NewBinding(
-			key.WithKeys("enter"),
-			key.WithHelp("enter", "view"),
-		),
-		edit: key.NewBinding(
-			key.WithKeys("e"),
-			key.WithHelp("e", "edit"),
-		),
-		delete: key.NewBinding(
-			key.WithKeys("d"),
-			key.WithHelp("d", "delete"),
-		),
+		view:   keys.get("party.view"),
+		edit:   keys.get("party.edit"),
+		delete: keys.get("party.delete"),
 	}
 }
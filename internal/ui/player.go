@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var _ tea.Model = (*playerView)(nil)
+
+// playerView is a read-only view of an encounter run in another program.
+type playerView struct {
+	Encounter
+
+	updates <-chan Encounter
+	theme   *Theme
+
+	width  int
+	height int
+	list   list.Model
+	help   help.Model
+	keys   playerViewKeyMap
+}
+
+// NewPlayerView returns a read-only model following the encounters published
+// to feed, and a function to stop following them once the model is no longer
+// running.
+func NewPlayerView(feed *EncounterFeed, opts ...Option) (tea.Model, func()) {
+	o := newOptions(opts)
+	updates, unsubscribe := feed.Subscribe()
+
+	initiativeList := list.New([]list.Item{}, &initiativeGroupItemDelegate{theme: o.theme}, 0, 0)
+	initiativeList.KeyMap = newListKeyMap()
+	initiativeList.SetShowTitle(false)
+	initiativeList.SetShowStatusBar(false)
+	initiativeList.SetShowHelp(false)
+	initiativeList.SetFilteringEnabled(false)
+	initiativeList.DisableQuitKeybindings()
+	o.theme.applyToList(&initiativeList)
+
+	return &playerView{
+		updates: updates,
+		theme:   o.theme,
+		list:    initiativeList,
+		help:    o.theme.newHelp(),
+		keys:    newPlayerViewKeyMap(o.keys),
+	}, unsubscribe
+}
+
+type encounterPublishedMsg struct {
+	encounter Encounter
+}
+
+func waitForEncounter(updates <-chan Encounter) tea.Cmd {
+	return func() tea.Msg {
+		e, ok := <-updates
+		if !ok {
+			return nil
+		}
+		return encounterPublishedMsg{encounter: e}
+	}
+}
+
+func (p playerView) Init() tea.Cmd {
+	return waitForEncounter(p.updates)
+}
+
+func (p playerView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width = msg.Width
+		p.height = msg.Height
+		return p, nil
+	case encounterPublishedMsg:
+		p.Encounter = msg.encounter
+		p.list.SetItems(initiativeGroupItems(p.Encounter))
+		p.list.Select(p.Turn)
+		return p, waitForEncounter(p.updates)
+	case tea.KeyMsg:
+		if key.Matches(msg, p.keys.quit) {
+			return p, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	p.list, cmd = p.list.Update(msg)
+	return p, cmd
+}
+
+func (p playerView) View() string {
+	p.help.Width = p.width
+	helpStyle := lipgloss.NewStyle().Padding(0, 1)
+	helpView := helpStyle.Render(p.help.View(p.keys))
+	availHeight := p.height - lipgloss.Height(helpView)
+
+	if len(p.IniativeGroups) == 0 && len(p.Waiting) == 0 {
+		placeholderStyle := lipgloss.NewStyle().
+			Italic(true).
+			Foreground(p.theme.subtle).
+			Align(lipgloss.Center)
+		content := placeholderStyle.Render("No encounter started...")
+		contentArea := lipgloss.NewStyle().
+			Height(availHeight).
+			Width(p.width).
+			AlignHorizontal(lipgloss.Center).
+			AlignVertical(lipgloss.Center).
+			Render(content)
+
+		return lipgloss.JoinVertical(lipgloss.Left, contentArea, helpView)
+	}
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(p.theme.title).
+		MarginBottom(1)
+	header := headerStyle.Render(fmt.Sprintf("Encounter: %s (Round %d)", p.Summary, p.Round))
+
+	if waiting := waitingView(p.Encounter, p.theme); waiting != "" {
+		helpView = lipgloss.JoinVertical(lipgloss.Left, waiting, helpView)
+		availHeight -= lipgloss.Height(waiting)
+	}
+
+	p.list.SetHeight(availHeight - lipgloss.Height(header))
+	p.list.SetWidth(p.width)
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, p.list.View(), helpView)
+}
+
+type playerViewKeyMap struct {
+	quit key.Binding
+}
+
+func newPlayerViewKeyMap(keys KeyBindings) playerViewKeyMap {
+	return playerViewKeyMap{
+		quit: keys.get("player.quit"),
+	}
+}
+
+func (k playerViewKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.quit}
+}
+
+func (k playerViewKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.quit},
+	}
+}
@@ -1,35 +1,216 @@
 package ui
 
 import (
-	"github.com/charmbracelet/bubbles/key"
+	"strings"
+	"time"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/google/uuid"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/termkit/skeleton"
 )
 
-func NewProgram() *tea.Program {
-	party := map[string]Character{
-		uuid.New().String(): Character{name: "Lorem"},
-		uuid.New().String(): Character{name: "Ipsum"},
+// Option configures the program returned by NewProgram.
+type Option func(*options)
+
+type options struct {
+	store              *Store
+	keys               KeyBindings
+	theme              *Theme
+	turnLimit          time.Duration
+	system             GameSystem
+	bestiary           *Bestiary
+	encounterObservers []func(Encounter)
+}
+
+func newOptions(opts []Option) options {
+	o := options{keys: DefaultKeyBindings(), theme: DefaultTheme(), system: DefaultGameSystem(), bestiary: DefaultBestiary()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.store == nil {
+		o.store = newMemoryStore()
+	}
+	return o
+}
+
+// WithStore sets the store holding the party and encounter history. Without
+// it, a sample party is used and nothing is saved.
+func WithStore(store *Store) Option {
+	return func(o *options) {
+		o.store = store
 	}
-	p := &party
+}
+
+// WithKeyBindings replaces the default key bindings.
+func WithKeyBindings(keys KeyBindings) Option {
+	return func(o *options) {
+		o.keys = keys
+	}
+}
+
+// WithTheme replaces the default theme.
+func WithTheme(theme *Theme) Option {
+	return func(o *options) {
+		o.theme = theme
+	}
+}
+
+// WithTurnLimit sets the turn limit new encounters start with.
+func WithTurnLimit(limit time.Duration) Option {
+	return func(o *options) {
+		o.turnLimit = limit
+	}
+}
+
+// WithGameSystem sets the game system new encounters follow by default.
+func WithGameSystem(system GameSystem) Option {
+	return func(o *options) {
+		o.system = system
+	}
+}
+
+// WithBestiary replaces the built-in monsters random encounters are
+// generated from.
+func WithBestiary(bestiary *Bestiary) Option {
+	return func(o *options) {
+		o.bestiary = bestiary
+	}
+}
+
+// WithEncounterObserver registers fn to be called with the running encounter
+// every time it changes, e.g. when it starts, ends or a turn is advanced.
+// fn is called from the program's update loop and must not block.
+func WithEncounterObserver(fn func(Encounter)) Option {
+	return func(o *options) {
+		o.encounterObservers = append(o.encounterObservers, fn)
+	}
+}
+
+func NewProgram(opts ...Option) *tea.Program {
+	return tea.NewProgram(NewModel(opts...), tea.WithMouseCellMotion())
+}
+
+// NewModel returns the tracker's root model, for running it in a program
+// other than the one created by NewProgram, e.g. over SSH.
+func NewModel(opts ...Option) tea.Model {
+	o := newOptions(opts)
+
+	p := &o.store.Party
 
 	s := skeleton.NewSkeleton()
 
 	s.SetPagePosition(lipgloss.Left)
+	o.theme.applyToSkeleton(s)
 
-	s.KeyMap.SwitchTabRight = key.NewBinding(
-		key.WithKeys("tab"))
+	s.KeyMap.SwitchTabRight = o.keys.get("tabs.next")
 
 	// To switch previous page
-	s.KeyMap.SwitchTabLeft = key.NewBinding(
-		key.WithKeys("shift+tab"))
+	s.KeyMap.SwitchTabLeft = o.keys.get("tabs.previous")
 
 	s.LockTabs().SetWrapTabs(true)
 
-	s.AddPage("encounter", "Encounter", newEncounter(s, p))
-	s.AddPage("party", "Party", newParty(s, p))
+	s.AddPage("encounter", "Encounter", newEncounter(s, p, o))
+	s.AddPage("party", "Party", newParty(s, p, o))
+	s.AddPage("history", "History", newHistory(s, &o.store.History, o))
+
+	return tracker{Skeleton: s, store: o.store, pages: []string{"encounter", "party", "history"}}
+}
+
+// dataFilePollInterval is how often the data file is checked for changes
+// made outside the tracker.
+const dataFilePollInterval = time.Second
+
+// tabsHeight is the height of the skeleton's tabs, drawn above the pages.
+const tabsHeight = 3
+
+// tracker is the root model. It watches the data file, reloading the store
+// when it changes on disk so that the pages pick up the changes the next
+// time they're updated, and switches pages when their tabs are clicked.
+type tracker struct {
+	*skeleton.Skeleton
+	store *Store
+	// pages are the keys of the pages, in the order of their tabs
+	pages []string
+}
+
+type dataFilePollMsg struct{}
+
+func pollDataFile() tea.Cmd {
+	return tea.Tick(dataFilePollInterval, func(time.Time) tea.Msg {
+		return dataFilePollMsg{}
+	})
+}
+
+func (t tracker) Init() tea.Cmd {
+	return tea.Batch(t.Skeleton.Init(), pollDataFile())
+}
+
+func (t tracker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(dataFilePollMsg); ok {
+		// a file that doesn't parse, e.g. one an editor is still writing, is
+		// tried again on the next poll
+		_ = t.store.Refresh()
+		return t, pollDataFile()
+	}
 
-	return tea.NewProgram(s)
+	if msg, ok := msg.(tea.MouseMsg); ok {
+		if msg.Y < tabsHeight {
+			return t, t.clickTab(msg)
+		}
+		// pages see the mouse relative to their top left corner, inside
+		// the skeleton's border
+		msg.X--
+		msg.Y -= tabsHeight
+		_, cmd := t.Skeleton.Update(msg)
+		return t, cmd
+	}
+
+	_, cmd := t.Skeleton.Update(msg)
+	return t, cmd
+}
+
+// clickTab switches to the page whose tab is clicked, unless the tabs are
+// locked, e.g. while an encounter is being created.
+func (t tracker) clickTab(msg tea.MouseMsg) tea.Cmd {
+	if !isClick(msg) {
+		return nil
+	}
+	i := tabAt(t.Skeleton.View(), msg.X)
+	if i < 0 || i >= len(t.pages) || t.IsTabsLocked() || t.IsTabLocked(t.pages[i]) {
+		return nil
+	}
+	t.SetActivePage(t.pages[i])
+	return t.IAMActivePageCmd()
+}
+
+// tabAt returns the index of the tab at column x of the skeleton's view, or
+// -1 if there's none. Tabs are told apart by the borders on either side of
+// their titles, ┤ Title ├, so that titles changed by the pages are followed.
+func tabAt(view string, x int) int {
+	lines := strings.Split(ansi.Strip(view), "\n")
+	if len(lines) < 2 {
+		return -1
+	}
+
+	tab, start, column := -1, 0, 0
+	for _, r := range lines[1] {
+		switch r {
+		case '┤':
+			tab++
+			start = column
+		case '├':
+			if tab >= 0 && x >= start && x <= column {
+				return tab
+			}
+		}
+		column += ansi.StringWidth(string(r))
+	}
+	return -1
+}
+
+func (o options) notifyEncounter(e Encounter) {
+	for _, fn := range o.encounterObservers {
+		fn(e)
+	}
 }
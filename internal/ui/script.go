@@ -0,0 +1,450 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Script drives an encounter with text commands instead of keystrokes, for
+// streaming and automated testing. Commands are one per line, such as
+// `add Goblin x3 init 12`, `next` or `dmg "Goblin 2" 7 fire`.
+//
+// The encounter starts out in round 0 while creatures are added, and the
+// first `next` starts it with the highest initiative.
+type Script struct {
+	Encounter Encounter
+	party     map[string]Character
+}
+
+// NewScript returns a script running a new encounter with the options'
+// party, game system and turn limit. Nothing is saved.
+func NewScript(opts ...Option) *Script {
+	o := newOptions(opts)
+	return &Script{
+		Encounter: Encounter{
+			ID:        uuid.New().String(),
+			StartedAt: time.Now(),
+			TurnLimit: o.turnLimit,
+			System:    o.system,
+		},
+		party: o.store.Party,
+	}
+}
+
+// commandArg is what an argument of a command names, for completing it.
+type commandArg int
+
+const (
+	argText commandArg = iota
+	argCreature
+	argCharacter
+	argCondition
+)
+
+// scriptCommand is a command of the script language.
+type scriptCommand struct {
+	name  string
+	usage string
+	args  []commandArg
+	run   func(s *Script, args []string) error
+}
+
+var scriptCommands = []scriptCommand{
+	{"add", "add <name> [x<count>] [+<modifier>] [init <initiative>]", []commandArg{argCharacter}, (*Script).add},
+	{"next", "next", nil, (*Script).next},
+	{"prev", "prev", nil, (*Script).prev},
+	{"dmg", "dmg <target> <amount> [type] [crit] [from <source>]", []commandArg{argCreature}, (*Script).damage},
+	{"heal", "heal <target> <amount> [from <source>]", []commandArg{argCreature}, (*Script).heal},
+	{"hp", "hp <target> <hp> [max hp]", []commandArg{argCreature}, (*Script).hp},
+	{"cond", "cond <target> <condition> [value]", []commandArg{argCreature, argCondition}, (*Script).condition},
+	{"end", "end", nil, (*Script).end},
+}
+
+// Run runs a line of the script. Blank lines and comments starting with #
+// do nothing.
+func (s *Script) Run(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	args, err := splitCommand(line)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(scriptCommands, func(c scriptCommand) bool { return c.name == strings.ToLower(args[0]) })
+	if i < 0 {
+		names := []string{}
+		for _, c := range scriptCommands {
+			names = append(names, c.name)
+		}
+		return fmt.Errorf("unknown command %q, expected one of %s", args[0], strings.Join(names, ", "))
+	}
+	if s.Encounter.Ended() {
+		return errors.New("the encounter has ended")
+	}
+	if err := scriptCommands[i].run(s, args[1:]); err != nil {
+		return fmt.Errorf("%w (usage: %s)", err, scriptCommands[i].usage)
+	}
+	return nil
+}
+
+// splitCommand splits a command into its words, keeping words in double
+// quotes together, such as "Goblin 2".
+func splitCommand(line string) ([]string, error) {
+	args := []string{}
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if line[0] != '"' {
+			arg, rest, _ := strings.Cut(line, " ")
+			args = append(args, arg)
+			line = rest
+			continue
+		}
+		arg, rest, ok := strings.Cut(line[1:], `"`)
+		if !ok {
+			return nil, fmt.Errorf("unterminated quote in %q", line)
+		}
+		args = append(args, arg)
+		line = rest
+	}
+	return args, nil
+}
+
+var (
+	monsterCount    = regexp.MustCompile(`^[xX×](\d+)$`)
+	monsterModifier = regexp.MustCompile(`^[+-]\d+$`)
+)
+
+// add adds a party character, or a group of monsters sharing an initiative,
+// rolling initiative unless it's given. Monsters roll with the modifier
+// given after their name, such as `add Goblin x3 +2`.
+func (s *Script) add(args []string) error {
+	name, count, modifier, initiative := []string{}, 1, "", 0
+	rolled := true
+	for i := 0; i < len(args); i++ {
+		switch {
+		case strings.EqualFold(args[i], "init") && i+1 < len(args):
+			value, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return fmt.Errorf("invalid initiative %q", args[i+1])
+			}
+			initiative, rolled = value, false
+			i++
+		case monsterCount.MatchString(args[i]):
+			count, _ = strconv.Atoi(monsterCount.FindStringSubmatch(args[i])[1])
+		case monsterModifier.MatchString(args[i]):
+			modifier = args[i]
+		default:
+			name = append(name, args[i])
+		}
+	}
+	if len(name) == 0 {
+		return errors.New("missing name")
+	}
+
+	group := IniativeGroup{}
+	if character, ok := s.character(strings.Join(name, " ")); ok && count == 1 && modifier == "" {
+		if slices.ContainsFunc(s.Encounter.Creatures(), func(c Creature) bool { return c.ID() == character.ID() }) {
+			return fmt.Errorf("%s is already in the encounter", character.Name())
+		}
+		group.Creatures = []Creature{character}
+	} else {
+		entry := strings.Join(name, " ")
+		if count > 1 {
+			entry = fmt.Sprintf("%s x%d", entry, count)
+		}
+		if modifier != "" {
+			entry = fmt.Sprintf("%s %s", entry, modifier)
+		}
+		monsters, err := parseMonsters(entry)
+		if err != nil || len(monsters) != 1 {
+			return fmt.Errorf("invalid monsters %q", entry)
+		}
+		group.Creatures = s.numberMonsters(monsters[0].name, monsters[0].monsters)
+	}
+
+	group.Iniative = initiative
+	if rolled {
+		group.Iniative = s.Encounter.Rules().RollInitiative(group.Modifiers(), false)
+	}
+	s.Encounter.AddGroup(group)
+	return nil
+}
+
+// numberMonsters numbers new monsters on from the creatures in the
+// encounter with the same name, so that no two creatures share a name: a
+// Goblin added after Goblin 1 to 3 becomes Goblin 4.
+func (s *Script) numberMonsters(name string, monsters []Creature) []Creature {
+	pattern := regexp.MustCompile(`^(?i)` + regexp.QuoteMeta(name) + `(?: (\d+))?$`)
+	last, found := 0, false
+	for _, creature := range s.Encounter.Creatures() {
+		match := pattern.FindStringSubmatch(creature.Name())
+		if match == nil {
+			continue
+		}
+		n := 1
+		if match[1] != "" {
+			n, _ = strconv.Atoi(match[1])
+		}
+		last, found = max(last, n), true
+	}
+	if !found {
+		return monsters
+	}
+
+	for i, creature := range monsters {
+		if monster, ok := creature.(Monster); ok {
+			monster.name = fmt.Sprintf("%s %d", name, last+i+1)
+			monsters[i] = monster
+		}
+	}
+	return monsters
+}
+
+// character returns the party character with the given name, ignoring case.
+func (s *Script) character(name string) (Character, bool) {
+	for _, character := range sortedCharacters(s.party) {
+		if strings.EqualFold(character.Name(), name) {
+			return character, true
+		}
+	}
+	return Character{}, false
+}
+
+// next starts the encounter, or advances to the next turn.
+func (s *Script) next(args []string) error {
+	if len(s.Encounter.IniativeGroups) == 0 {
+		return errors.New("no creatures in the encounter")
+	}
+	if s.Encounter.Round == 0 {
+		s.Encounter.Round = 1
+		s.Encounter.Turn = 0
+		s.Encounter.TurnStartedAt = time.Now()
+		s.Encounter.skipSurprised()
+		return nil
+	}
+	s.Encounter.NextTurn()
+	return nil
+}
+
+// prev goes back to the previous turn.
+func (s *Script) prev(args []string) error {
+	if s.Encounter.Round == 0 {
+		return errors.New("the encounter hasn't started")
+	}
+	s.Encounter.PreviousTurn()
+	return nil
+}
+
+// damage records damage done to a creature.
+func (s *Script) damage(args []string) error {
+	event, rest, err := s.event(DamageEvent, args)
+	if err != nil {
+		return err
+	}
+	for _, arg := range rest {
+		switch {
+		case strings.EqualFold(arg, "crit"):
+			event.Critical = true
+		case event.DamageType == "":
+			event.DamageType = strings.ToLower(arg)
+		default:
+			return fmt.Errorf("unexpected %q", arg)
+		}
+	}
+	s.Encounter.Record(event)
+	return nil
+}
+
+// heal records healing done to a creature.
+func (s *Script) heal(args []string) error {
+	event, rest, err := s.event(HealingEvent, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected %q", rest[0])
+	}
+	s.Encounter.Record(event)
+	return nil
+}
+
+// event parses the target, amount and source of damage or healing,
+// returning the arguments left over.
+func (s *Script) event(kind CombatEventKind, args []string) (CombatEvent, []string, error) {
+	if len(args) < 2 {
+		return CombatEvent{}, nil, errors.New("missing target or amount")
+	}
+	target, err := s.creature(args[0])
+	if err != nil {
+		return CombatEvent{}, nil, err
+	}
+	amount, err := strconv.Atoi(args[1])
+	if err != nil || amount < 0 {
+		return CombatEvent{}, nil, fmt.Errorf("invalid amount %q", args[1])
+	}
+	event := CombatEvent{Kind: kind, Target: target.ID(), Amount: amount}
+
+	rest := args[2:]
+	if i := slices.IndexFunc(rest, func(arg string) bool { return strings.EqualFold(arg, "from") }); i >= 0 {
+		if i+1 >= len(rest) {
+			return CombatEvent{}, nil, errors.New("missing source")
+		}
+		source, err := s.creature(rest[i+1])
+		if err != nil {
+			return CombatEvent{}, nil, err
+		}
+		event.Source = source.ID()
+		rest = slices.Delete(slices.Clone(rest), i, i+2)
+	}
+	return event, rest, nil
+}
+
+// hp tracks a creature's hit points, its maximum being its current hit
+// points unless given.
+func (s *Script) hp(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return errors.New("missing target or hit points")
+	}
+	target, err := s.creature(args[0])
+	if err != nil {
+		return err
+	}
+	hp, err := strconv.Atoi(args[1])
+	if err != nil || hp < 0 {
+		return fmt.Errorf("invalid hit points %q", args[1])
+	}
+	maxHP := hp
+	if len(args) == 3 {
+		if maxHP, err = strconv.Atoi(args[2]); err != nil || maxHP < 0 {
+			return fmt.Errorf("invalid maximum hit points %q", args[2])
+		}
+	}
+	s.Encounter.SetHealth(target.ID(), hp, maxHP)
+	return nil
+}
+
+// condition toggles a creature's condition. A value gives the condition
+// that value, zero taking it away.
+func (s *Script) condition(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return errors.New("missing target or condition")
+	}
+	target, err := s.creature(args[0])
+	if err != nil {
+		return err
+	}
+	conditions := s.Encounter.Rules().Conditions()
+	i := slices.IndexFunc(conditions, func(c ConditionType) bool { return strings.EqualFold(c.Name, args[1]) })
+	if i < 0 {
+		return fmt.Errorf("unknown condition %q in %s", args[1], s.Encounter.Rules().Title())
+	}
+	condition := conditions[i]
+
+	has := hasCondition(s.Encounter.Conditions[target.ID()], condition.Name)
+	value := 0
+	if !has {
+		value = 1
+	}
+	if len(args) == 3 {
+		if value, err = strconv.Atoi(args[2]); err != nil || value < 0 {
+			return fmt.Errorf("invalid value %q", args[2])
+		}
+	}
+
+	switch {
+	case condition.Valued:
+		s.Encounter.ToggleCondition(target.ID(), condition, value)
+	case (value > 0) != has:
+		s.Encounter.ToggleCondition(target.ID(), condition, 0)
+	}
+	return nil
+}
+
+// end ends the encounter.
+func (s *Script) end(args []string) error {
+	s.Encounter.End()
+	return nil
+}
+
+// creature returns the creature in the encounter with the given name,
+// ignoring case. A name shared by more than one creature is an error rather
+// than a guess.
+func (s *Script) creature(name string) (Creature, error) {
+	matches := []Creature{}
+	for _, creature := range s.Encounter.Creatures() {
+		if strings.EqualFold(creature.Name(), name) {
+			matches = append(matches, creature)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no creature named %q", name)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("%d creatures are named %q", len(matches), name)
+}
+
+// State describes the encounter as plain text: the round and whose turn it
+// is, then the initiative order with the creatures' hit points and
+// conditions.
+func (s *Script) State() string {
+	e := s.Encounter
+	var b strings.Builder
+
+	group, ok := e.ActiveGroup()
+	switch {
+	case e.Ended():
+		fmt.Fprintf(&b, "Ended after round %d\n", e.Round)
+	case e.Round == 0:
+		b.WriteString("Not started\n")
+	case ok:
+		names := []string{}
+		for _, creature := range group.Creatures {
+			names = append(names, creature.Name())
+		}
+		fmt.Fprintf(&b, "Round %d, %s to act\n", e.Round, strings.Join(names, ", "))
+	default:
+		fmt.Fprintf(&b, "Round %d\n", e.Round)
+	}
+
+	for i, group := range e.IniativeGroups {
+		marker := " "
+		if i == e.Turn && e.Round > 0 && !e.Ended() {
+			marker = ">"
+		}
+		creatures := []string{}
+		for _, creature := range group.Creatures {
+			creatures = append(creatures, s.describe(creature))
+		}
+		fmt.Fprintf(&b, "%s %3d  %s\n", marker, group.Iniative, strings.Join(creatures, ", "))
+	}
+	for _, waiting := range e.Waiting {
+		fmt.Fprintf(&b, "  %s: %s\n", waiting.Action, s.describe(waiting.Creature))
+	}
+	return b.String()
+}
+
+// describe describes a creature with its hit points and conditions, such as
+// "Goblin 2 [3/7 HP, Prone]".
+func (s *Script) describe(creature Creature) string {
+	notes := []string{}
+	if h, ok := s.Encounter.Health[creature.ID()]; ok {
+		notes = append(notes, h.describe(s.Encounter.Rules()))
+	}
+	for _, condition := range s.Encounter.Conditions[creature.ID()] {
+		notes = append(notes, condition.String())
+	}
+	if len(notes) == 0 {
+		return creature.Name()
+	}
+	return creature.Name() + " [" + strings.Join(notes, ", ") + "]"
+}
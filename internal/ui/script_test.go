@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		// want is the state after the last line, or wantErr what running
+		// it fails with
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "add monsters",
+			lines: []string{"add goblin x3 init 12"},
+			want:  "Not started\n   12  goblin 1, goblin 2, goblin 3\n",
+		},
+		{
+			name:  "damage",
+			lines: []string{"add Goblin x3 init 12", `hp "Goblin 2" 10`, `dmg "Goblin 2" 7 fire`},
+			want:  "Not started\n   12  Goblin 1, Goblin 2 [3/10 HP], Goblin 3\n",
+		},
+		{
+			name:  "condition",
+			lines: []string{"add Lorem init 15", "cond Lorem prone 1"},
+			want:  "Not started\n   15  Lorem [Prone]\n",
+		},
+		{
+			name:  "number monsters on",
+			lines: []string{"add Goblin init 12", "add Goblin x2 init 8", "next"},
+			want:  "Round 1, Goblin to act\n>  12  Goblin\n    8  Goblin 2, Goblin 3\n",
+		},
+		{
+			name:    "ambiguous name",
+			lines:   []string{"add Goblin x2 init 12", `add "Goblin 2" init 15`, `dmg "Goblin 2" 7`},
+			wantErr: `2 creatures are named "Goblin 2"`,
+		},
+		{
+			name:    "unterminated quote",
+			lines:   []string{"add Goblin x3 init 12", `dmg "Goblin 2 7`},
+			wantErr: "unterminated quote",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the party has a character sharing a monster's name, to make
+			// names ambiguous
+			s := NewScript(WithStore(&Store{Party: map[string]Character{
+				"lorem":    {id: "lorem", name: "Lorem"},
+				"goblin-2": {id: "goblin-2", name: "Goblin 2"},
+			}}))
+			last := len(tt.lines) - 1
+			for _, line := range tt.lines[:last] {
+				if err := s.Run(line); err != nil {
+					t.Fatalf("Run(%q) error = %v", line, err)
+				}
+			}
+
+			err := s.Run(tt.lines[last])
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run(%q) error = %v, want %q", tt.lines[last], err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run(%q) error = %v", tt.lines[last], err)
+			}
+			if got := s.State(); got != tt.want {
+				t.Errorf("State() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// CreatureStats holds a creature's combat statistics over one or more
+// encounters.
+type CreatureStats struct {
+	DamageDealt int
+	DamageTaken int
+	HealingDone int
+	Kills       int
+	Crits       int
+	Turns       int
+	// TurnTime is the total time the creature's turns took.
+	TurnTime time.Duration
+	// Encounters is the number of encounters the creature took part in.
+	Encounters int
+}
+
+// AverageTurn returns the average duration of the creature's turns.
+func (s CreatureStats) AverageTurn() time.Duration {
+	if s.Turns == 0 {
+		return 0
+	}
+	return s.TurnTime / time.Duration(s.Turns)
+}
+
+func (s *CreatureStats) add(other CreatureStats) {
+	s.DamageDealt += other.DamageDealt
+	s.DamageTaken += other.DamageTaken
+	s.HealingDone += other.HealingDone
+	s.Kills += other.Kills
+	s.Crits += other.Crits
+	s.Turns += other.Turns
+	s.TurnTime += other.TurnTime
+	s.Encounters += other.Encounters
+}
+
+// Stats returns the statistics of every creature in the encounter, by
+// creature ID.
+func (e Encounter) Stats() map[string]CreatureStats {
+	stats := map[string]CreatureStats{}
+	for _, creature := range e.Creatures() {
+		s := CreatureStats{Encounters: 1, Turns: len(e.TurnDurations[creature.ID()])}
+		for _, d := range e.TurnDurations[creature.ID()] {
+			s.TurnTime += d
+		}
+		stats[creature.ID()] = s
+	}
+
+	for _, event := range e.Log {
+		source := stats[event.Source]
+		switch event.Kind {
+		case DamageEvent:
+			source.DamageDealt += event.Amount
+			if event.Critical {
+				source.Crits++
+			}
+			if event.Killed {
+				source.Kills++
+			}
+		case HealingEvent:
+			source.HealingDone += event.Amount
+		}
+		// Events without a source, such as a trap going off, only count
+		// towards the target
+		if event.Source != "" {
+			stats[event.Source] = source
+		}
+
+		if event.Kind == DamageEvent {
+			target := stats[event.Target]
+			target.DamageTaken += event.Amount
+			stats[event.Target] = target
+		}
+	}
+
+	return stats
+}
+
+// CampaignStats adds up the statistics of every creature across the
+// encounters, by creature ID.
+func CampaignStats(encounters []Encounter) map[string]CreatureStats {
+	stats := map[string]CreatureStats{}
+	for _, e := range encounters {
+		for id, s := range e.Stats() {
+			total := stats[id]
+			total.add(s)
+			stats[id] = total
+		}
+	}
+	return stats
+}
+
+// statsTable renders the statistics of the encounter's creatures in
+// initiative order, followed by the creatures waiting to rejoin it.
+func statsTable(e Encounter, theme *Theme) string {
+	stats := e.Stats()
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.title).Padding(0, 1)
+	cellStyle := lipgloss.NewStyle().Foreground(theme.text).Padding(0, 1)
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(theme.subtle)).
+		Headers("Creature", "Dealt", "Taken", "Healed", "Kills", "Crits", "Turns", "Avg turn").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return headerStyle
+			}
+			if col > 0 {
+				return cellStyle.Align(lipgloss.Right)
+			}
+			return cellStyle
+		})
+
+	for _, creature := range e.Creatures() {
+		s := stats[creature.ID()]
+		t.Row(
+			creature.Name(),
+			strconv.Itoa(s.DamageDealt),
+			strconv.Itoa(s.DamageTaken),
+			strconv.Itoa(s.HealingDone),
+			strconv.Itoa(s.Kills),
+			strconv.Itoa(s.Crits),
+			strconv.Itoa(s.Turns),
+			formatDuration(s.AverageTurn()),
+		)
+	}
+
+	return t.Render()
+}
+
+// statsView renders the statistics table followed by as much of the most
+// recent combat log as fits in height.
+func statsView(e Encounter, theme *Theme, height int) string {
+	stats := statsTable(e, theme)
+
+	logStyle := lipgloss.NewStyle().Foreground(theme.subtle)
+	lines := []string{}
+	for i := len(e.Log) - 1; i >= 0 && len(lines) < height-lipgloss.Height(stats)-1; i-- {
+		lines = append([]string{logStyle.Render(eventDescription(e, e.Log[i]))}, lines...)
+	}
+
+	content := stats
+	if len(lines) > 0 {
+		content = lipgloss.JoinVertical(lipgloss.Left, stats, "", strings.Join(lines, "\n"))
+	}
+	return lipgloss.NewStyle().Height(height).MaxHeight(height).Render(content)
+}
+
+// campaignStatsView renders a character's statistics across the campaign.
+func campaignStatsView(s CreatureStats, theme *Theme) string {
+	if s.Encounters == 0 {
+		return lipgloss.NewStyle().Italic(true).Foreground(theme.subtle).Render("No encounters yet...")
+	}
+
+	labelStyle := lipgloss.NewStyle().Foreground(theme.subtle).Width(16)
+	valueStyle := lipgloss.NewStyle().Foreground(theme.text)
+
+	lines := []string{}
+	for _, stat := range []struct {
+		label string
+		value string
+	}{
+		{"Encounters", strconv.Itoa(s.Encounters)},
+		{"Damage dealt", strconv.Itoa(s.DamageDealt)},
+		{"Damage taken", strconv.Itoa(s.DamageTaken)},
+		{"Healing done", strconv.Itoa(s.HealingDone)},
+		{"Kills", strconv.Itoa(s.Kills)},
+		{"Crits", strconv.Itoa(s.Crits)},
+		{"Turns", strconv.Itoa(s.Turns)},
+		{"Average turn", formatDuration(s.AverageTurn())},
+	} {
+		lines = append(lines, labelStyle.Render(stat.label)+valueStyle.Render(stat.value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// eventDescription describes a logged combat event using the creatures'
+// names.
+func eventDescription(e Encounter, event CombatEvent) string {
+	creatures := e.Creatures()
+	name := func(id string) string {
+		if i := slices.IndexFunc(creatures, func(c Creature) bool { return c.ID() == id }); i >= 0 {
+			return creatures[i].Name()
+		}
+		return "Something"
+	}
+
+	var text string
+	switch event.Kind {
+	case DamageEvent:
+		text = fmt.Sprintf("%s hit %s for %d", name(event.Source), name(event.Target), event.Amount)
+		if event.Critical {
+			text += " (crit)"
+		}
+		if event.Killed {
+			text += ", killing them"
+		}
+	case HealingEvent:
+		text = fmt.Sprintf("%s healed %s for %d", name(event.Source), name(event.Target), event.Amount)
+	}
+	return fmt.Sprintf("Round %d: %s", event.Round, text)
+}
@@ -0,0 +1,789 @@
+package ui
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrDataChanged is returned when saving over a data file that was changed
+// on disk since it was loaded, such as by a script. The store can then be
+// reloaded or merged with the changes.
+var ErrDataChanged = errors.New("data file changed on disk")
+
+// Store holds the party and the history of past encounters, and persists
+// them to a data file.
+type Store struct {
+	path string
+	// checksum is the checksum of the data file as last loaded or saved,
+	// empty when it didn't exist
+	checksum string
+	// base is the party as last loaded or saved, for merging with changes
+	// made on disk since
+	base map[string]Character
+	// version is bumped every time the party and history are replaced with
+	// the contents of the data file, so that views know to refresh
+	version int
+	// unsaved reports whether there are changes here that haven't been
+	// saved, such as when saving found the data file changed on disk
+	unsaved bool
+	// seen is the stamp of the data file when it was last read or written,
+	// so that checking it for changes only reads it when that changes
+	seen fileStamp
+
+	Party   map[string]Character
+	History []Encounter
+}
+
+// newMemoryStore returns a store that isn't persisted, with a sample party.
+func newMemoryStore() *Store {
+	party := map[string]Character{}
+	for _, name := range []string{"Lorem", "Ipsum"} {
+		id := uuid.New().String()
+		party[id] = Character{id: id, name: name, level: 1}
+	}
+	return &Store{Party: party}
+}
+
+// LoadStore reads the store from the data file at path. A missing file
+// results in an empty store, created on the first save.
+func LoadStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReadStore reads the party and history from the data file at path into a
+// store that isn't persisted, for encounters that aren't saved. A file
+// written by an older version of the tracker is only upgraded in memory.
+func ReadStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	unlock, err := lockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	c, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Party: c.party, History: c.history}, nil
+}
+
+// Reload replaces the party and history with the contents of the data file,
+// discarding unsaved changes. A file written by an older version of the
+// tracker is upgraded, after backing it up.
+func (s *Store) Reload() error {
+	if s.path == "" {
+		return nil
+	}
+
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s.seen = s.stamp()
+	c, err := s.read()
+	if err != nil {
+		return err
+	}
+	s.Party, s.History = c.party, c.history
+	if c.version < dataVersion {
+		if err := s.backup(c); err != nil {
+			return err
+		}
+		return s.write()
+	}
+	s.loaded(c.checksum)
+	return nil
+}
+
+// Refresh reloads the data file when it was changed on disk since it was
+// loaded, such as by a co-DM editing it. The file is only read when its
+// modification time or size changed since it was last read or written,
+// which is cheap enough to check often. It leaves the store alone while
+// there are unsaved changes, which are up to the user to merge or discard,
+// and when the file was deleted, leaving it to be recreated by the next
+// save. A file written by an older version of the tracker isn't upgraded
+// underneath whoever wrote it: the next save reports it as changed.
+func (s *Store) Refresh() error {
+	if s.path == "" || s.unsaved || s.stamp() == s.seen {
+		return nil
+	}
+
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// a file that can't be read isn't read again until it changes
+	s.seen = s.stamp()
+	c, err := s.read()
+	if err != nil || c.checksum == s.checksum || c.checksum == "" || c.version < dataVersion {
+		return err
+	}
+	s.Party, s.History = c.party, c.history
+	s.loaded(c.checksum)
+	return nil
+}
+
+// Save writes the store to its data file, unless it isn't persisted. It
+// returns ErrDataChanged without writing anything when the file was changed
+// on disk since it was loaded.
+func (s *Store) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	// cleared once written
+	s.unsaved = true
+
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	c, err := s.read()
+	if err != nil {
+		return err
+	}
+	if c.checksum != s.checksum {
+		return fmt.Errorf("%s: %w", s.path, ErrDataChanged)
+	}
+	return s.write()
+}
+
+// Merge saves the store over a data file that was changed on disk since it
+// was loaded, keeping the changes made on both sides. Characters added,
+// edited or deleted here since the file was loaded are taken from this
+// store, and every other character from the file. Encounters archived on
+// either side are kept.
+func (s *Store) Merge() error {
+	if s.path == "" {
+		return nil
+	}
+
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	c, err := s.read()
+	if err != nil {
+		return err
+	}
+	if c.version < dataVersion {
+		if err := s.backup(c); err != nil {
+			return err
+		}
+	}
+	party, history := c.party, c.history
+
+	for id, character := range s.Party {
+		if base, ok := s.base[id]; !ok || base != character {
+			party[id] = character
+		}
+	}
+	for id := range s.base {
+		if _, ok := s.Party[id]; !ok {
+			delete(party, id)
+		}
+	}
+	for _, e := range s.History {
+		if !slices.ContainsFunc(history, func(other Encounter) bool { return other.ID == e.ID }) {
+			history = append(history, e)
+		}
+	}
+
+	s.Party, s.History = party, history
+	s.version++
+	return s.write()
+}
+
+// dataContents is what the data file holds, as read by the store.
+type dataContents struct {
+	party   map[string]Character
+	history []Encounter
+	// data and checksum are the contents of the file as they are on disk,
+	// empty when it doesn't exist
+	data     []byte
+	checksum string
+	// version is the data version the file was written with, which was
+	// upgraded in memory when it's older than the current one
+	version int
+}
+
+// read reads the party and history from the data file. A missing file reads
+// as an empty party and history, and a file written by an older version of
+// the tracker is upgraded in memory, leaving it to the caller to write it.
+func (s *Store) read() (dataContents, error) {
+	c := dataContents{party: map[string]Character{}, version: dataVersion}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	c.data, c.checksum = data, checksum(data)
+	if data, c.version, err = migrate(s.path, data); err != nil {
+		return c, err
+	}
+
+	var file dataFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return c, fmt.Errorf("parsing %s: %w", s.path, err)
+	}
+
+	for _, record := range file.Party {
+		c.party[record.ID] = record.character()
+	}
+	for _, record := range file.History {
+		c.history = append(c.history, record.encounter())
+	}
+	return c, nil
+}
+
+// write writes the store to its data file, replacing it atomically so that
+// readers never see a partly written file.
+func (s *Store) write() error {
+	file := dataFile{
+		Version: dataVersion,
+		Party:   []characterRecord{},
+		History: []encounterRecord{},
+	}
+	for _, character := range sortedCharacters(s.Party) {
+		file.Party = append(file.Party, newCharacterRecord(character))
+	}
+	for _, encounter := range s.History {
+		file.History = append(file.History, newEncounterRecord(encounter))
+	}
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return err
+	}
+	s.seen = s.stamp()
+	s.loaded(checksum(data))
+	return nil
+}
+
+// loaded records that the party and history match the data file with the
+// given checksum.
+func (s *Store) loaded(checksum string) {
+	s.checksum = checksum
+	s.base = maps.Clone(s.Party)
+	s.unsaved = false
+	s.version++
+}
+
+// fileStamp tells versions of a file apart without reading it, by its
+// modification time and size.
+type fileStamp struct {
+	modTime int64
+	size    int64
+}
+
+// stamp returns the stamp of the data file, the zero stamp when it doesn't
+// exist.
+func (s *Store) stamp() fileStamp {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Archive adds an ended encounter to the history and saves the store.
+func (s *Store) Archive(e Encounter) error {
+	s.History = append(s.History, e.clone())
+	return s.Save()
+}
+
+// journalPath returns the path of the journal next to the data file, e.g.
+// data.journal.yaml for data.yaml.
+func (s *Store) journalPath() string {
+	return strings.TrimSuffix(s.path, filepath.Ext(s.path)) + ".journal.yaml"
+}
+
+// Journal writes the encounter in progress to the journal, so that it can
+// be resumed if the program exits before it ends. Nothing is written unless
+// the store is persisted.
+func (s *Store) Journal(e Encounter) error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := yaml.Marshal(journalFile{Version: dataVersion, Encounter: newEncounterRecord(e)})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.journalPath(), data)
+}
+
+// ClearJournal removes the journal once the encounter in progress has ended.
+func (s *Store) ClearJournal() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.Remove(s.journalPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Interrupted returns the encounter left in the journal by a program that
+// exited before it ended, and false if there is none. A journal written by an
+// older version of the tracker is upgraded in memory, and one written by a
+// newer version is refused like the data file.
+func (s *Store) Interrupted() (Encounter, bool, error) {
+	if s.path == "" {
+		return Encounter{}, false, nil
+	}
+
+	data, err := os.ReadFile(s.journalPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return Encounter{}, false, nil
+	}
+	if err != nil {
+		return Encounter{}, false, err
+	}
+
+	if data, err = migrateJournal(s.journalPath(), data); err != nil {
+		return Encounter{}, false, err
+	}
+
+	var file journalFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return Encounter{}, false, fmt.Errorf("parsing %s: %w", s.journalPath(), err)
+	}
+	return file.Encounter.encounter(), true, nil
+}
+
+// sortedCharacters returns the characters of a party ordered by name, so the
+// data file doesn't change order between saves.
+func sortedCharacters(party map[string]Character) []Character {
+	characters := make([]Character, 0, len(party))
+	for _, character := range party {
+		characters = append(characters, character)
+	}
+	slices.SortFunc(characters, func(a, b Character) int {
+		return cmp.Or(strings.Compare(a.name, b.name), strings.Compare(a.id, b.id))
+	})
+	return characters
+}
+
+// dataFile is the layout of the data file.
+type dataFile struct {
+	// Version is the version of the layout, see migrations.
+	Version int               `yaml:"version"`
+	Party   []characterRecord `yaml:"party"`
+	History []encounterRecord `yaml:"history"`
+}
+
+// journalFile is the layout of the journal.
+type journalFile struct {
+	// Version is the version of the layout, shared with the data file.
+	Version   int             `yaml:"version"`
+	Encounter encounterRecord `yaml:"encounter"`
+}
+
+type characterRecord struct {
+	ID         string           `yaml:"id"`
+	Name       string           `yaml:"name"`
+	Initiative initiativeRecord `yaml:"initiative,omitempty"`
+	Notes      string           `yaml:"notes,omitempty"`
+	Level      int              `yaml:"level,omitempty"`
+	ArmorClass int              `yaml:"armor_class,omitempty"`
+	StatBlock  string           `yaml:"stat_block,omitempty"`
+}
+
+func newCharacterRecord(c Character) characterRecord {
+	return characterRecord{
+		ID:         c.id,
+		Name:       c.name,
+		Initiative: initiativeRecord(c.initiative),
+		Notes:      c.notes,
+		Level:      c.level,
+		ArmorClass: c.armorClass,
+		StatBlock:  c.statBlock,
+	}
+}
+
+func (r characterRecord) character() Character {
+	return Character{
+		id:         r.ID,
+		name:       r.Name,
+		initiative: Initiative(r.Initiative),
+		notes:      r.Notes,
+		level:      r.Level,
+		armorClass: r.ArmorClass,
+		statBlock:  r.StatBlock,
+	}
+}
+
+type initiativeRecord struct {
+	Modifier  int  `yaml:"modifier,omitempty"`
+	Bonus     int  `yaml:"bonus,omitempty"`
+	Advantage bool `yaml:"advantage,omitempty"`
+	Swap      bool `yaml:"swap,omitempty"`
+}
+
+type encounterRecord struct {
+	ID            string                       `yaml:"id"`
+	Summary       string                       `yaml:"summary"`
+	Notes         string                       `yaml:"notes,omitempty"`
+	StartedAt     time.Time                    `yaml:"started_at"`
+	EndedAt       time.Time                    `yaml:"ended_at,omitempty"`
+	Round         int                          `yaml:"round"`
+	Turn          int                          `yaml:"turn"`
+	TurnStartedAt time.Time                    `yaml:"turn_started_at,omitempty"`
+	TurnLimit     time.Duration                `yaml:"turn_limit,omitempty"`
+	TurnDurations map[string][]time.Duration   `yaml:"turn_durations,omitempty"`
+	Groups        []groupRecord                `yaml:"groups"`
+	Log           []eventRecord                `yaml:"log,omitempty"`
+	Waiting       []waitingRecord              `yaml:"waiting,omitempty"`
+	Surprised     []string                     `yaml:"surprised,omitempty"`
+	SurpriseRules string                       `yaml:"surprise_rules,omitempty"`
+	Mode          string                       `yaml:"mode,omitempty"`
+	Reroll        string                       `yaml:"reroll,omitempty"`
+	Acted         []string                     `yaml:"acted,omitempty"`
+	System        string                       `yaml:"system,omitempty"`
+	Health        map[string]healthRecord      `yaml:"health,omitempty"`
+	Conditions    map[string][]conditionRecord `yaml:"conditions,omitempty"`
+}
+
+func newEncounterRecord(e Encounter) encounterRecord {
+	r := encounterRecord{
+		ID:            e.ID,
+		Summary:       e.Summary,
+		Notes:         e.Notes,
+		StartedAt:     e.StartedAt,
+		EndedAt:       e.EndedAt,
+		Round:         e.Round,
+		Turn:          e.Turn,
+		TurnStartedAt: e.TurnStartedAt,
+		TurnLimit:     e.TurnLimit,
+		TurnDurations: e.TurnDurations,
+		Groups:        []groupRecord{},
+		Surprised:     e.Surprised,
+		Acted:         e.Acted,
+		System:        e.Rules().Name(),
+	}
+	if e.SurpriseRules == SurpriseDisadvantage {
+		r.SurpriseRules = surpriseRulesDisadvantage
+	}
+	switch e.Mode {
+	case SideInitiative:
+		r.Mode = initiativeModeSide
+	case PopcornInitiative:
+		r.Mode = initiativeModePopcorn
+	}
+	switch e.Reroll {
+	case RerollAuto:
+		r.Reroll = rerollAuto
+	case RerollPrompt:
+		r.Reroll = rerollPrompt
+	}
+	for _, group := range e.IniativeGroups {
+		g := groupRecord{Initiative: group.Iniative, Side: group.Side, Creatures: []creatureRecord{}}
+		for _, creature := range group.Creatures {
+			g.Creatures = append(g.Creatures, newCreatureRecord(creature))
+		}
+		r.Groups = append(r.Groups, g)
+	}
+	for _, event := range e.Log {
+		r.Log = append(r.Log, newEventRecord(event))
+	}
+	for _, waiting := range e.Waiting {
+		r.Waiting = append(r.Waiting, newWaitingRecord(waiting))
+	}
+	for id, h := range e.Health {
+		if r.Health == nil {
+			r.Health = map[string]healthRecord{}
+		}
+		r.Health[id] = healthRecord(h)
+	}
+	for id, conditions := range e.Conditions {
+		if r.Conditions == nil {
+			r.Conditions = map[string][]conditionRecord{}
+		}
+		for _, c := range conditions {
+			r.Conditions[id] = append(r.Conditions[id], conditionRecord(c))
+		}
+	}
+	return r
+}
+
+func (r encounterRecord) encounter() Encounter {
+	e := Encounter{
+		ID:            r.ID,
+		Summary:       r.Summary,
+		Notes:         r.Notes,
+		StartedAt:     r.StartedAt,
+		EndedAt:       r.EndedAt,
+		Round:         r.Round,
+		Turn:          r.Turn,
+		TurnStartedAt: r.TurnStartedAt,
+		TurnLimit:     r.TurnLimit,
+		TurnDurations: r.TurnDurations,
+		Surprised:     r.Surprised,
+		Acted:         r.Acted,
+	}
+	if r.SurpriseRules == surpriseRulesDisadvantage {
+		e.SurpriseRules = SurpriseDisadvantage
+	}
+	switch r.Mode {
+	case initiativeModeSide:
+		e.Mode = SideInitiative
+	case initiativeModePopcorn:
+		e.Mode = PopcornInitiative
+	}
+	switch r.Reroll {
+	case rerollAuto:
+		e.Reroll = RerollAuto
+	case rerollPrompt:
+		e.Reroll = RerollPrompt
+	}
+	for _, g := range r.Groups {
+		group := IniativeGroup{Iniative: g.Initiative, Side: g.Side}
+		for _, creature := range g.Creatures {
+			group.Creatures = append(group.Creatures, creature.creature())
+		}
+		e.IniativeGroups = append(e.IniativeGroups, group)
+	}
+	for _, event := range r.Log {
+		e.Log = append(e.Log, event.event())
+	}
+	for _, waiting := range r.Waiting {
+		e.Waiting = append(e.Waiting, waiting.waiting())
+	}
+	// Unknown game systems fall back to the default
+	e.System, _ = NewGameSystem(r.System)
+	for id, h := range r.Health {
+		if e.Health == nil {
+			e.Health = map[string]Health{}
+		}
+		e.Health[id] = Health(h)
+	}
+	for id, conditions := range r.Conditions {
+		for _, c := range conditions {
+			e.SetConditions(id, append(e.Conditions[id], Condition(c)))
+		}
+	}
+	return e
+}
+
+// surpriseRulesDisadvantage marks encounters using the 2024 surprise rules.
+// Encounters without it skip the first turn of surprised creatures.
+const surpriseRulesDisadvantage = "disadvantage"
+
+// Encounters using side or popcorn initiative. Encounters without either use
+// individual initiative.
+const (
+	initiativeModeSide    = "side"
+	initiativeModePopcorn = "popcorn"
+)
+
+// Encounters that reroll initiative every round. Encounters without either
+// keep their initiative order.
+const (
+	rerollAuto   = "auto"
+	rerollPrompt = "prompt"
+)
+
+type healthRecord struct {
+	HP      int  `yaml:"hp"`
+	MaxHP   int  `yaml:"max_hp"`
+	Dying   int  `yaml:"dying,omitempty"`
+	Wounded int  `yaml:"wounded,omitempty"`
+	Dead    bool `yaml:"dead,omitempty"`
+}
+
+type conditionRecord struct {
+	Name  string `yaml:"name"`
+	Value int    `yaml:"value,omitempty"`
+}
+
+const (
+	eventKindDamage  = "damage"
+	eventKindHealing = "healing"
+)
+
+type eventRecord struct {
+	Kind       string `yaml:"kind"`
+	Round      int    `yaml:"round"`
+	Source     string `yaml:"source,omitempty"`
+	Target     string `yaml:"target"`
+	Amount     int    `yaml:"amount"`
+	DamageType string `yaml:"damage_type,omitempty"`
+	Critical   bool   `yaml:"critical,omitempty"`
+	Killed     bool   `yaml:"killed,omitempty"`
+}
+
+func newEventRecord(e CombatEvent) eventRecord {
+	kind := eventKindDamage
+	if e.Kind == HealingEvent {
+		kind = eventKindHealing
+	}
+	return eventRecord{
+		Kind:       kind,
+		Round:      e.Round,
+		Source:     e.Source,
+		Target:     e.Target,
+		Amount:     e.Amount,
+		DamageType: e.DamageType,
+		Critical:   e.Critical,
+		Killed:     e.Killed,
+	}
+}
+
+func (r eventRecord) event() CombatEvent {
+	kind := DamageEvent
+	if r.Kind == eventKindHealing {
+		kind = HealingEvent
+	}
+	return CombatEvent{
+		Kind:       kind,
+		Round:      r.Round,
+		Source:     r.Source,
+		Target:     r.Target,
+		Amount:     r.Amount,
+		DamageType: r.DamageType,
+		Critical:   r.Critical,
+		Killed:     r.Killed,
+	}
+}
+
+const (
+	waitActionDelay = "delay"
+	waitActionReady = "ready"
+)
+
+type waitingRecord struct {
+	creatureRecord `yaml:",inline"`
+	Action         string `yaml:"action"`
+	Initiative     int    `yaml:"initiative"`
+}
+
+func newWaitingRecord(w WaitingCreature) waitingRecord {
+	action := waitActionDelay
+	if w.Action == Ready {
+		action = waitActionReady
+	}
+	return waitingRecord{
+		creatureRecord: newCreatureRecord(w.Creature),
+		Action:         action,
+		Initiative:     w.Initiative,
+	}
+}
+
+func (r waitingRecord) waiting() WaitingCreature {
+	action := Delay
+	if r.Action == waitActionReady {
+		action = Ready
+	}
+	return WaitingCreature{Creature: r.creature(), Action: action, Initiative: r.Initiative}
+}
+
+type groupRecord struct {
+	Initiative int              `yaml:"initiative"`
+	Side       string           `yaml:"side,omitempty"`
+	Creatures  []creatureRecord `yaml:"creatures"`
+}
+
+const (
+	creatureKindCharacter = "character"
+	creatureKindMonster   = "monster"
+)
+
+type creatureRecord struct {
+	Kind string `yaml:"kind"`
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+	// Modifiers is what the creature adds to their initiative rolls, named
+	// so as not to clash with a waiting creature's initiative.
+	Modifiers  initiativeRecord `yaml:"modifiers,omitempty"`
+	Notes      string           `yaml:"notes,omitempty"`
+	ArmorClass int              `yaml:"armor_class,omitempty"`
+	StatBlock  string           `yaml:"stat_block,omitempty"`
+}
+
+func newCreatureRecord(c Creature) creatureRecord {
+	r := creatureRecord{ID: c.ID(), Name: c.Name(), Notes: c.Notes(), ArmorClass: c.ArmorClass(), StatBlock: c.StatBlock()}
+	switch c := c.(type) {
+	case Character:
+		r.Kind = creatureKindCharacter
+		r.Modifiers = initiativeRecord(c.initiative)
+	case Monster:
+		r.Kind = creatureKindMonster
+		r.Modifiers = initiativeRecord(c.initiative)
+	}
+	return r
+}
+
+func (r creatureRecord) creature() Creature {
+	if r.Kind == creatureKindCharacter {
+		return Character{id: r.ID, name: r.Name, initiative: Initiative(r.Modifiers), notes: r.Notes, armorClass: r.ArmorClass, statBlock: r.StatBlock}
+	}
+	return Monster{id: r.ID, name: r.Name, initiative: Initiative(r.Modifiers), notes: r.Notes, armorClass: r.ArmorClass, statBlock: r.StatBlock}
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeKeepsChangesFromBothSides(t *testing.T) {
+	tests := []struct {
+		name string
+		// mine and theirs change the party of two stores loaded from the
+		// same file; theirs is saved first and mine is merged over it
+		mine, theirs func(party map[string]Character)
+	}{
+		{
+			name:   "deleted here, edited on disk",
+			mine:   func(party map[string]Character) { delete(party, "ipsum") },
+			theirs: func(party map[string]Character) { party["lorem"] = withLevel(party["lorem"], 5) },
+		},
+		{
+			name:   "edited here, deleted on disk",
+			mine:   func(party map[string]Character) { party["lorem"] = withLevel(party["lorem"], 5) },
+			theirs: func(party map[string]Character) { delete(party, "ipsum") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.yaml")
+			initial, err := LoadStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			initial.Party = map[string]Character{
+				"lorem": {id: "lorem", name: "Lorem", level: 1},
+				"ipsum": {id: "ipsum", name: "Ipsum", level: 1},
+			}
+			if err := initial.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			mine, err := LoadStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			theirs, err := LoadStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.theirs(theirs.Party)
+			if err := theirs.Save(); err != nil {
+				t.Fatal(err)
+			}
+			tt.mine(mine.Party)
+			if err := mine.Save(); !errors.Is(err, ErrDataChanged) {
+				t.Fatalf("Save() error = %v, want ErrDataChanged", err)
+			}
+			if err := mine.Merge(); err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+
+			merged, err := LoadStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := merged.Party["ipsum"]; ok {
+				t.Error("merged party still has Ipsum")
+			}
+			if got := merged.Party["lorem"].Level(); got != 5 {
+				t.Errorf("merged Lorem is level %d, want 5", got)
+			}
+			if len(merged.Party) != 1 {
+				t.Errorf("merged party has %d characters, want 1", len(merged.Party))
+			}
+		})
+	}
+}
+
+func withLevel(c Character, level int) Character {
+	c.level = level
+	return c
+}
@@ -0,0 +1,557 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GameSystem holds the rules of a tabletop RPG that the encounter logic
+// consults: how initiative is rolled, which conditions creatures can have,
+// what happens at 0 HP and what a creature can do on its turn.
+type GameSystem interface {
+	// Name identifies the system in the configuration and data files.
+	Name() string
+	// Title is the system's name as shown to the user.
+	Title() string
+
+	// InitiativeName is what the modifier initiative is rolled with is
+	// called, such as "Perception".
+	InitiativeName() string
+	// RollInitiative rolls initiative with the creature's initiative
+	// modifiers, with disadvantage when the creature is surprised.
+	RollInitiative(i Initiative, disadvantage bool) int
+	// BreakTie orders two groups with the same initiative, returning a
+	// negative number when a goes first, a positive number when b goes
+	// first, or zero to keep their order.
+	BreakTie(a, b IniativeGroup) int
+
+	// Conditions lists the conditions a creature can have.
+	Conditions() []ConditionType
+	// EndTurn returns a creature's conditions once its turn has ended, such
+	// as Frightened going down by one.
+	EndTurn(conditions []Condition) []Condition
+
+	// Damage returns the creature's health after taking damage, given its
+	// conditions, some of which bring death closer.
+	Damage(h Health, conditions []Condition, amount int, critical bool) Health
+	// Heal returns the creature's health after being healed.
+	Heal(h Health, amount int) Health
+	// Status describes how close to death a creature at 0 HP is, such as
+	// "dying 2", or nothing when it's up.
+	Status(h Health) string
+
+	// Actions lists what a creature with the given conditions can do on
+	// its turn.
+	Actions(conditions []Condition) []string
+
+	// ParseChallenge parses how dangerous a monster is, such as its
+	// challenge rating "1/4" in 5e or its level in PF2e.
+	ParseChallenge(str string) (float64, error)
+	// Difficulties names how hard encounters can be, easiest first.
+	Difficulties() []string
+	// EncounterBudget returns the least a group of monsters costs for an
+	// encounter of a difficulty, an index of Difficulties, against a party
+	// of characters with the given levels.
+	EncounterBudget(levels []int, difficulty int) int
+	// EncounterCost returns what a group of monsters with the given
+	// challenges costs against a party of characters with the given
+	// levels.
+	EncounterCost(levels []int, challenges []float64) int
+}
+
+// gameSystems are the supported game systems, the first being the default.
+var gameSystems = []GameSystem{fifthEdition{}, pathfinder2e{}}
+
+// DefaultGameSystem returns the game system used when none is configured.
+func DefaultGameSystem() GameSystem {
+	return gameSystems[0]
+}
+
+// NewGameSystem returns the named game system. An empty name selects the
+// default game system.
+func NewGameSystem(name string) (GameSystem, error) {
+	if name == "" {
+		return DefaultGameSystem(), nil
+	}
+	i := slices.IndexFunc(gameSystems, func(s GameSystem) bool { return s.Name() == name })
+	if i < 0 {
+		return nil, fmt.Errorf("unknown game system %q", name)
+	}
+	return gameSystems[i], nil
+}
+
+// ConditionType is a condition a creature can have.
+type ConditionType struct {
+	Name string
+	// Valued reports whether the condition has a value, such as
+	// Frightened 2.
+	Valued bool
+}
+
+// Condition is a condition a creature has.
+type Condition struct {
+	Name string
+	// Value is the condition's value, or zero when it has none.
+	Value int
+}
+
+func (c Condition) String() string {
+	if c.Value > 0 {
+		return fmt.Sprintf("%s %d", c.Name, c.Value)
+	}
+	return c.Name
+}
+
+// hasCondition reports whether any of the conditions is one of names.
+func hasCondition(conditions []Condition, names ...string) bool {
+	return slices.ContainsFunc(conditions, func(c Condition) bool { return slices.Contains(names, c.Name) })
+}
+
+// conditionValue returns the value of the named condition, or zero.
+func conditionValue(conditions []Condition, name string) int {
+	if i := slices.IndexFunc(conditions, func(c Condition) bool { return c.Name == name }); i >= 0 {
+		return conditions[i].Value
+	}
+	return 0
+}
+
+// Health is a creature's hit points and how close it is to death.
+type Health struct {
+	HP    int
+	MaxHP int
+	// Dying counts towards death while at 0 HP: failed death saves in 5e,
+	// the dying value in PF2e.
+	Dying int
+	// Wounded is the wounded value in PF2e, which adds to the dying value
+	// every time the creature drops to 0 HP.
+	Wounded int
+	Dead    bool
+}
+
+// describe describes the hit points and status under the game system, such
+// as "0/24 HP, dying 2".
+func (h Health) describe(system GameSystem) string {
+	parts := []string{fmt.Sprintf("%d/%d HP", h.HP, h.MaxHP)}
+	if status := system.Status(h); status != "" {
+		parts = append(parts, status)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fifthEdition is Dungeons & Dragons 5th edition.
+type fifthEdition struct{}
+
+func (fifthEdition) Name() string           { return "5e" }
+func (fifthEdition) Title() string          { return "D&D 5e" }
+func (fifthEdition) InitiativeName() string { return "Initiative" }
+
+func (fifthEdition) RollInitiative(i Initiative, disadvantage bool) int {
+	return i.Roll(disadvantage)
+}
+
+// BreakTie leaves ties for the DM to decide.
+func (fifthEdition) BreakTie(a, b IniativeGroup) int {
+	return 0
+}
+
+func (fifthEdition) Conditions() []ConditionType {
+	return []ConditionType{
+		{Name: "Blinded"},
+		{Name: "Charmed"},
+		{Name: "Deafened"},
+		{Name: "Exhaustion", Valued: true},
+		{Name: "Frightened"},
+		{Name: "Grappled"},
+		{Name: "Incapacitated"},
+		{Name: "Invisible"},
+		{Name: "Paralyzed"},
+		{Name: "Petrified"},
+		{Name: "Poisoned"},
+		{Name: "Prone"},
+		{Name: "Restrained"},
+		{Name: "Stunned"},
+		{Name: "Unconscious"},
+	}
+}
+
+func (fifthEdition) EndTurn(conditions []Condition) []Condition {
+	return conditions
+}
+
+// Damage drops the creature to 0 HP, killing it outright when the damage
+// left over is at least its hit point maximum. Damage at 0 HP is a failed
+// death save, two on a critical hit, and three failed saves kill it.
+func (fifthEdition) Damage(h Health, conditions []Condition, amount int, critical bool) Health {
+	if h.Dead {
+		return h
+	}
+	if h.HP > 0 {
+		h.HP -= amount
+		if h.HP > 0 {
+			return h
+		}
+		h.Dead = -h.HP >= h.MaxHP
+		h.HP = 0
+		return h
+	}
+
+	h.Dying++
+	if critical {
+		h.Dying++
+	}
+	h.Dead = h.Dying >= 3 || amount >= h.MaxHP
+	return h
+}
+
+// Heal brings the creature back up, resetting its death saves.
+func (fifthEdition) Heal(h Health, amount int) Health {
+	if h.Dead {
+		return h
+	}
+	h.HP = min(h.HP+amount, h.MaxHP)
+	h.Dying = 0
+	return h
+}
+
+func (fifthEdition) Status(h Health) string {
+	switch {
+	case h.Dead:
+		return "dead"
+	case h.HP > 0:
+		return ""
+	case h.Dying == 1:
+		return "unconscious, 1 failed death save"
+	case h.Dying > 1:
+		return fmt.Sprintf("unconscious, %d failed death saves", h.Dying)
+	}
+	return "unconscious"
+}
+
+// Actions is an action, a bonus action, movement and a reaction, none of
+// which an incapacitated creature can take.
+func (fifthEdition) Actions(conditions []Condition) []string {
+	if hasCondition(conditions, "Incapacitated", "Paralyzed", "Petrified", "Stunned", "Unconscious") {
+		return nil
+	}
+	actions := []string{"Action", "Bonus action", "Movement", "Reaction"}
+	if hasCondition(conditions, "Grappled", "Restrained") || conditionValue(conditions, "Exhaustion") >= 5 {
+		actions = slices.DeleteFunc(actions, func(a string) bool { return a == "Movement" })
+	}
+	return actions
+}
+
+// fifthEditionXP is the experience monsters are worth by challenge rating.
+var fifthEditionXP = map[float64]int{
+	0: 10, 0.125: 25, 0.25: 50, 0.5: 100,
+	1: 200, 2: 450, 3: 700, 4: 1100, 5: 1800,
+	6: 2300, 7: 2900, 8: 3900, 9: 5000, 10: 5900,
+	11: 7200, 12: 8400, 13: 10000, 14: 11500, 15: 13000,
+	16: 15000, 17: 18000, 18: 20000, 19: 22000, 20: 25000,
+	21: 33000, 22: 41000, 23: 50000, 24: 62000, 25: 75000,
+	26: 90000, 27: 105000, 28: 120000, 29: 135000, 30: 155000,
+}
+
+// fifthEditionThresholds are the experience thresholds of a character for
+// an easy, medium, hard and deadly encounter, by level.
+var fifthEditionThresholds = [20][4]int{
+	{25, 50, 75, 100},
+	{50, 100, 150, 200},
+	{75, 150, 225, 400},
+	{125, 250, 375, 500},
+	{250, 500, 750, 1100},
+	{300, 600, 900, 1400},
+	{350, 750, 1100, 1700},
+	{450, 900, 1400, 2100},
+	{550, 1100, 1600, 2400},
+	{600, 1200, 1900, 2800},
+	{800, 1600, 2400, 3600},
+	{1000, 2000, 3000, 4500},
+	{1100, 2200, 3400, 5100},
+	{1250, 2500, 3800, 5700},
+	{1400, 2800, 4300, 6400},
+	{1600, 3200, 4800, 7200},
+	{2000, 3900, 5900, 8800},
+	{2100, 4200, 6300, 9500},
+	{2400, 4900, 7300, 10900},
+	{2800, 5700, 8500, 12700},
+}
+
+// fifthEditionMultipliers multiply the experience of a group of monsters,
+// more of them being more dangerous than their experience suggests.
+var fifthEditionMultipliers = []float64{0.5, 1, 1.5, 2, 2.5, 3, 4, 5}
+
+// ParseChallenge parses a challenge rating, such as 5 or 1/4.
+func (fifthEdition) ParseChallenge(str string) (float64, error) {
+	challenge := 0.0
+	switch str = strings.TrimSpace(str); str {
+	case "1/8":
+		challenge = 0.125
+	case "1/4":
+		challenge = 0.25
+	case "1/2":
+		challenge = 0.5
+	default:
+		rating, err := strconv.Atoi(str)
+		if err != nil {
+			return 0, fmt.Errorf("invalid challenge rating %q", str)
+		}
+		challenge = float64(rating)
+	}
+	if _, ok := fifthEditionXP[challenge]; !ok {
+		return 0, fmt.Errorf("invalid challenge rating %q", str)
+	}
+	return challenge, nil
+}
+
+func (fifthEdition) Difficulties() []string {
+	return []string{"Easy", "Medium", "Hard", "Deadly"}
+}
+
+// EncounterBudget adds up the characters' experience thresholds.
+func (fifthEdition) EncounterBudget(levels []int, difficulty int) int {
+	budget := 0
+	for _, level := range levels {
+		budget += fifthEditionThresholds[min(max(level, 1), 20)-1][difficulty]
+	}
+	return budget
+}
+
+// EncounterCost is the monsters' experience, multiplied by how many there
+// are against how many characters.
+func (fifthEdition) EncounterCost(levels []int, challenges []float64) int {
+	xp := 0
+	for _, challenge := range challenges {
+		xp += fifthEditionXP[challenge]
+	}
+
+	multiplier := 6
+	switch n := len(challenges); {
+	case n <= 1:
+		multiplier = 1
+	case n == 2:
+		multiplier = 2
+	case n <= 6:
+		multiplier = 3
+	case n <= 10:
+		multiplier = 4
+	case n <= 14:
+		multiplier = 5
+	}
+	switch {
+	case len(levels) < 3:
+		multiplier++
+	case len(levels) >= 6:
+		multiplier--
+	}
+	return int(float64(xp) * fifthEditionMultipliers[multiplier])
+}
+
+// pathfinder2e is Pathfinder 2nd edition.
+type pathfinder2e struct{}
+
+func (pathfinder2e) Name() string           { return "pf2e" }
+func (pathfinder2e) Title() string          { return "Pathfinder 2e" }
+func (pathfinder2e) InitiativeName() string { return "Perception" }
+
+// RollInitiative rolls a Perception check. There's no advantage in PF2e.
+func (pathfinder2e) RollInitiative(i Initiative, disadvantage bool) int {
+	return rollD20() + i.Total()
+}
+
+// BreakTie has enemies go before player characters.
+func (pathfinder2e) BreakTie(a, b IniativeGroup) int {
+	isParty := func(g IniativeGroup) bool {
+		return len(g.Creatures) > 0 && !slices.ContainsFunc(g.Creatures, func(c Creature) bool {
+			_, ok := c.(Character)
+			return !ok
+		})
+	}
+	switch {
+	case isParty(a) && !isParty(b):
+		return 1
+	case isParty(b) && !isParty(a):
+		return -1
+	}
+	return 0
+}
+
+func (pathfinder2e) Conditions() []ConditionType {
+	return []ConditionType{
+		{Name: "Blinded"},
+		{Name: "Clumsy", Valued: true},
+		{Name: "Concealed"},
+		{Name: "Confused"},
+		{Name: "Controlled"},
+		{Name: "Dazzled"},
+		{Name: "Deafened"},
+		{Name: "Doomed", Valued: true},
+		{Name: "Drained", Valued: true},
+		{Name: "Enfeebled", Valued: true},
+		{Name: "Fascinated"},
+		{Name: "Fatigued"},
+		{Name: "Fleeing"},
+		{Name: "Frightened", Valued: true},
+		{Name: "Grabbed"},
+		{Name: "Hidden"},
+		{Name: "Immobilized"},
+		{Name: "Invisible"},
+		{Name: "Off-Guard"},
+		{Name: "Paralyzed"},
+		{Name: "Petrified"},
+		{Name: "Prone"},
+		{Name: "Quickened"},
+		{Name: "Restrained"},
+		{Name: "Sickened", Valued: true},
+		{Name: "Slowed", Valued: true},
+		{Name: "Stunned", Valued: true},
+		{Name: "Stupefied", Valued: true},
+		{Name: "Unconscious"},
+	}
+}
+
+// EndTurn lowers Frightened by one.
+func (pathfinder2e) EndTurn(conditions []Condition) []Condition {
+	ended := []Condition{}
+	for _, c := range conditions {
+		if c.Name == "Frightened" {
+			c.Value--
+			if c.Value <= 0 {
+				continue
+			}
+		}
+		ended = append(ended, c)
+	}
+	return ended
+}
+
+// pf2eDeath is the dying value at which a creature dies, unless it's
+// doomed.
+const pf2eDeath = 4
+
+// Damage drops the creature to 0 HP with a dying value of 1, 2 on a
+// critical hit, plus its wounded value. Damage while dying raises the dying
+// value by 1, 2 on a critical hit, and the creature dies at dying 4, less
+// its doomed value.
+func (pathfinder2e) Damage(h Health, conditions []Condition, amount int, critical bool) Health {
+	if h.Dead {
+		return h
+	}
+	increase := 1
+	if critical {
+		increase = 2
+	}
+
+	if h.HP > 0 {
+		h.HP -= amount
+		if h.HP > 0 {
+			return h
+		}
+		h.HP = 0
+		h.Dying = increase + h.Wounded
+	} else {
+		h.Dying += increase
+	}
+	h.Dead = h.Dying >= max(pf2eDeath-conditionValue(conditions, "Doomed"), 1)
+	return h
+}
+
+// Heal brings a dying creature back up, raising its wounded value by one.
+func (pathfinder2e) Heal(h Health, amount int) Health {
+	if h.Dead {
+		return h
+	}
+	if h.Dying > 0 {
+		h.Dying = 0
+		h.Wounded++
+	}
+	h.HP = min(h.HP+amount, h.MaxHP)
+	return h
+}
+
+func (pathfinder2e) Status(h Health) string {
+	parts := []string{}
+	switch {
+	case h.Dead:
+		return "dead"
+	case h.Dying > 0:
+		parts = append(parts, fmt.Sprintf("dying %d", h.Dying))
+	case h.HP == 0:
+		parts = append(parts, "unconscious")
+	}
+	if h.Wounded > 0 {
+		parts = append(parts, fmt.Sprintf("wounded %d", h.Wounded))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Actions is three actions and a reaction, fewer actions when slowed or
+// stunned and one more when quickened.
+func (pathfinder2e) Actions(conditions []Condition) []string {
+	if hasCondition(conditions, "Paralyzed", "Petrified", "Unconscious") {
+		return nil
+	}
+	count := 3 - conditionValue(conditions, "Slowed") - conditionValue(conditions, "Stunned")
+	if hasCondition(conditions, "Quickened") {
+		count++
+	}
+
+	actions := []string{}
+	if count > 0 {
+		actions = append(actions, strings.Repeat("◆", count))
+	}
+	return append(actions, "Reaction")
+}
+
+// pathfinder2eBudgets are the experience budgets of encounters for a party
+// of four, by difficulty, and what each character more or less adds.
+var pathfinder2eBudgets = []struct{ budget, adjustment int }{
+	{40, 10}, {60, 15}, {80, 20}, {120, 30}, {160, 40},
+}
+
+// pathfinder2eXP is the experience a creature is worth by its level against
+// the party's level, from four levels below to four levels above it.
+var pathfinder2eXP = []int{10, 15, 20, 30, 40, 60, 80, 120, 160}
+
+// ParseChallenge parses a creature's level, from -1 to 25.
+func (pathfinder2e) ParseChallenge(str string) (float64, error) {
+	level, err := strconv.Atoi(strings.TrimSpace(str))
+	if err != nil || level < -1 || level > 25 {
+		return 0, fmt.Errorf("invalid creature level %q", str)
+	}
+	return float64(level), nil
+}
+
+func (pathfinder2e) Difficulties() []string {
+	return []string{"Trivial", "Low", "Moderate", "Severe", "Extreme"}
+}
+
+func (pathfinder2e) EncounterBudget(levels []int, difficulty int) int {
+	b := pathfinder2eBudgets[difficulty]
+	return max(b.budget+(len(levels)-4)*b.adjustment, b.adjustment)
+}
+
+// EncounterCost adds up the creatures' experience against the party's
+// average level. Creatures more than four levels below it are worth
+// nothing, and those more than four levels above it more than any budget.
+func (pathfinder2e) EncounterCost(levels []int, challenges []float64) int {
+	partyLevel := 0
+	for _, level := range levels {
+		partyLevel += level
+	}
+	partyLevel = int(math.Round(float64(partyLevel) / float64(max(len(levels), 1))))
+
+	xp := 0
+	for _, challenge := range challenges {
+		switch difference := int(challenge) - partyLevel; {
+		case difference < -4:
+		case difference > 4:
+			xp += math.MaxInt32
+		default:
+			xp += pathfinder2eXP[difference+4]
+		}
+	}
+	return xp
+}
@@ -0,0 +1,207 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/termkit/skeleton"
+
+	"initiative/internal/config"
+)
+
+// Theme holds the colors every view and form is drawn with.
+type Theme struct {
+	title      lipgloss.TerminalColor
+	initiative lipgloss.TerminalColor
+	selected   lipgloss.TerminalColor
+	text       lipgloss.TerminalColor
+	subtle     lipgloss.TerminalColor
+	error      lipgloss.TerminalColor
+}
+
+var themes = map[string]Theme{
+	"default": {
+		title:      lipgloss.AdaptiveColor{Light: "162", Dark: "205"},
+		initiative: lipgloss.AdaptiveColor{Light: "166", Dark: "214"},
+		selected:   lipgloss.AdaptiveColor{Light: "127", Dark: "170"},
+		text:       lipgloss.AdaptiveColor{Light: "235", Dark: "252"},
+		subtle:     lipgloss.AdaptiveColor{Light: "245", Dark: "240"},
+		error:      lipgloss.AdaptiveColor{Light: "#d70000", Dark: "#ff5555"},
+	},
+	"high-contrast": {
+		title:      lipgloss.AdaptiveColor{Light: "0", Dark: "15"},
+		initiative: lipgloss.AdaptiveColor{Light: "4", Dark: "11"},
+		selected:   lipgloss.AdaptiveColor{Light: "5", Dark: "14"},
+		text:       lipgloss.AdaptiveColor{Light: "0", Dark: "15"},
+		subtle:     lipgloss.AdaptiveColor{Light: "8", Dark: "7"},
+		error:      lipgloss.AdaptiveColor{Light: "1", Dark: "9"},
+	},
+}
+
+// noColorTheme is used when the NO_COLOR environment variable is set.
+var noColorTheme = Theme{
+	title:      lipgloss.NoColor{},
+	initiative: lipgloss.NoColor{},
+	selected:   lipgloss.NoColor{},
+	text:       lipgloss.NoColor{},
+	subtle:     lipgloss.NoColor{},
+	error:      lipgloss.NoColor{},
+}
+
+// DefaultTheme returns the theme used when none is configured.
+func DefaultTheme() *Theme {
+	theme, _ := NewTheme("", nil)
+	return theme
+}
+
+// NewTheme returns the named theme, looking it up in custom before the
+// built-in themes. An empty name selects the default theme. When the
+// NO_COLOR environment variable is set, a theme without colors is returned
+// instead.
+func NewTheme(name string, custom map[string]config.Theme) (*Theme, error) {
+	if name == "" {
+		name = "default"
+	}
+
+	theme, ok := themes[name]
+	if c, isCustom := custom[name]; isCustom {
+		theme = themes["default"]
+		for _, color := range []struct {
+			from config.Color
+			to   *lipgloss.TerminalColor
+		}{
+			{c.Title, &theme.title},
+			{c.Initiative, &theme.initiative},
+			{c.Selected, &theme.selected},
+			{c.Text, &theme.text},
+			{c.Subtle, &theme.subtle},
+			{c.Error, &theme.error},
+		} {
+			if !color.from.IsZero() {
+				*color.to = lipgloss.AdaptiveColor{Light: color.from.Light, Dark: color.from.Dark}
+			}
+		}
+	} else if !ok {
+		return nil, fmt.Errorf("unknown theme %q", name)
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		theme = noColorTheme
+	}
+
+	return &theme, nil
+}
+
+// newHelp returns a help model drawn with the theme.
+func (t *Theme) newHelp() help.Model {
+	h := help.New()
+	h.Styles.ShortKey = lipgloss.NewStyle().Foreground(t.text)
+	h.Styles.FullKey = h.Styles.ShortKey
+	h.Styles.ShortDesc = lipgloss.NewStyle().Foreground(t.subtle)
+	h.Styles.FullDesc = h.Styles.ShortDesc
+	h.Styles.ShortSeparator = lipgloss.NewStyle().Foreground(t.subtle)
+	h.Styles.FullSeparator = h.Styles.ShortSeparator
+	h.Styles.Ellipsis = h.Styles.ShortSeparator
+	return h
+}
+
+// applyToList draws the list's own elements, such as the filter prompt and
+// pagination, with the theme.
+func (t *Theme) applyToList(l *list.Model) {
+	l.Styles.FilterPrompt = l.Styles.FilterPrompt.Foreground(t.selected)
+	l.Styles.FilterCursor = l.Styles.FilterCursor.Foreground(t.selected)
+	l.Styles.StatusBar = l.Styles.StatusBar.Foreground(t.subtle)
+	l.Styles.StatusEmpty = l.Styles.StatusEmpty.Foreground(t.subtle)
+	l.Styles.StatusBarActiveFilter = l.Styles.StatusBarActiveFilter.Foreground(t.text)
+	l.Styles.StatusBarFilterCount = l.Styles.StatusBarFilterCount.Foreground(t.subtle)
+	l.Styles.NoItems = l.Styles.NoItems.Foreground(t.subtle)
+	l.Styles.ArabicPagination = l.Styles.ArabicPagination.Foreground(t.subtle)
+	l.Styles.ActivePaginationDot = l.Styles.ActivePaginationDot.Foreground(t.text)
+	l.Styles.InactivePaginationDot = l.Styles.InactivePaginationDot.Foreground(t.subtle)
+	l.Styles.DividerDot = l.Styles.DividerDot.Foreground(t.subtle)
+	l.Help = t.newHelp()
+}
+
+// renderError renders an error to show below a view.
+func (t *Theme) renderError(err error) string {
+	return lipgloss.NewStyle().Foreground(t.error).Padding(0, 1).Render("Error: " + err.Error())
+}
+
+// formTheme returns the theme for huh forms.
+func (t *Theme) formTheme() *huh.Theme {
+	theme := huh.ThemeCharm()
+	if isNoColor(t.text) {
+		// start without colors, which the theme doesn't override everywhere
+		theme = huh.ThemeBase()
+	}
+
+	theme.Focused.Base = theme.Focused.Base.BorderForeground(t.subtle)
+	theme.Focused.Card = theme.Focused.Base
+	theme.Focused.Title = theme.Focused.Title.Foreground(t.title)
+	theme.Focused.NoteTitle = theme.Focused.NoteTitle.Foreground(t.title)
+	theme.Focused.Directory = theme.Focused.Directory.Foreground(t.title)
+	theme.Focused.Description = theme.Focused.Description.Foreground(t.subtle)
+	theme.Focused.ErrorIndicator = theme.Focused.ErrorIndicator.Foreground(t.error)
+	// Remove the leading space of the error message
+	theme.Focused.ErrorMessage = lipgloss.NewStyle().SetString("*").Foreground(t.error)
+	theme.Focused.SelectSelector = theme.Focused.SelectSelector.Foreground(t.selected)
+	theme.Focused.NextIndicator = theme.Focused.NextIndicator.Foreground(t.selected)
+	theme.Focused.PrevIndicator = theme.Focused.PrevIndicator.Foreground(t.selected)
+	theme.Focused.Option = theme.Focused.Option.Foreground(t.text)
+	theme.Focused.MultiSelectSelector = theme.Focused.MultiSelectSelector.Foreground(t.selected)
+	theme.Focused.SelectedOption = theme.Focused.SelectedOption.Foreground(t.initiative)
+	theme.Focused.SelectedPrefix = theme.Focused.SelectedPrefix.Foreground(t.initiative)
+	theme.Focused.UnselectedPrefix = theme.Focused.UnselectedPrefix.Foreground(t.subtle)
+	theme.Focused.UnselectedOption = theme.Focused.UnselectedOption.Foreground(t.text)
+	theme.Focused.FocusedButton = theme.Focused.FocusedButton.Foreground(t.text).Background(t.selected).Reverse(isNoColor(t.selected))
+	theme.Focused.Next = theme.Focused.FocusedButton
+	theme.Focused.BlurredButton = theme.Focused.BlurredButton.Foreground(t.text).Background(t.subtle)
+	theme.Focused.TextInput.Cursor = theme.Focused.TextInput.Cursor.Foreground(t.selected)
+	theme.Focused.TextInput.Placeholder = theme.Focused.TextInput.Placeholder.Foreground(t.subtle)
+	theme.Focused.TextInput.Prompt = theme.Focused.TextInput.Prompt.Foreground(t.selected)
+
+	theme.Blurred = theme.Focused
+	theme.Blurred.Base = theme.Focused.Base.BorderStyle(lipgloss.HiddenBorder())
+	theme.Blurred.Card = theme.Blurred.Base
+	theme.Blurred.NextIndicator = lipgloss.NewStyle()
+	theme.Blurred.PrevIndicator = lipgloss.NewStyle()
+
+	theme.Group.Title = theme.Focused.Title
+	theme.Group.Description = theme.Focused.Description
+
+	return theme
+}
+
+// applyToSkeleton draws the skeleton's border and tabs with the theme.
+func (t *Theme) applyToSkeleton(s *skeleton.Skeleton) {
+	s.SetBorderColor(colorString(t.subtle)).
+		SetActiveTabTextColor(colorString(t.title)).
+		SetActiveTabBorderColor(colorString(t.title)).
+		SetInactiveTabTextColor(colorString(t.text)).
+		SetInactiveTabBorderColor(colorString(t.subtle))
+}
+
+// colorString returns a color as the skeleton takes it, the light or dark
+// variant of an adaptive color depending on the terminal's background, and
+// an empty string for no color.
+func colorString(c lipgloss.TerminalColor) string {
+	switch c := c.(type) {
+	case lipgloss.AdaptiveColor:
+		if lipgloss.HasDarkBackground() {
+			return c.Dark
+		}
+		return c.Light
+	case lipgloss.Color:
+		return string(c)
+	}
+	return ""
+}
+
+func isNoColor(c lipgloss.TerminalColor) bool {
+	_, ok := c.(lipgloss.NoColor)
+	return ok
+}
@@ -1,27 +1,326 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"initiative/internal/config"
+	"initiative/internal/server"
 	"initiative/internal/ui"
+	"io"
+	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/ssh"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 )
 
 var dataFile string
 
+var configFile string
+
+// programOptions returns the UI options set in the configuration file along
+// with the store loaded from the data file by load.
+func programOptions(load func(path string) (*ui.Store, error)) ([]ui.Option, error) {
+	store, err := loadStore(load)
+	if err != nil {
+		return nil, err
+	}
+
+	path := configFile
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := ui.NewKeyBindings(cfg.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	theme, err := ui.NewTheme(cfg.Theme, cfg.Themes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	system, err := ui.NewGameSystem(cfg.System)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	builtinMonsters := cfg.BuiltinMonsters == nil || *cfg.BuiltinMonsters
+	bestiary, err := ui.NewBestiary(cfg.Monsters, builtinMonsters, system)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return []ui.Option{
+		ui.WithStore(store),
+		ui.WithKeyBindings(keys),
+		ui.WithTheme(theme),
+		ui.WithTurnLimit(cfg.TurnLimit),
+		ui.WithGameSystem(system),
+		ui.WithBestiary(bestiary),
+	}, nil
+}
+
+// loadStore loads the party and encounter history from the data file with
+// load, either ui.LoadStore or ui.ReadStore.
+func loadStore(load func(path string) (*ui.Store, error)) (*ui.Store, error) {
+	path := dataFile
+	if path == "" {
+		var err error
+		if path, err = config.DefaultDataPath(); err != nil {
+			return nil, err
+		}
+	}
+
+	return load(path)
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "initiative",
 	Short: "A CLI tool for managing tabletop RPG initiative tracking",
-	Run: func(cmd *cobra.Command, args []string) {
-		p := ui.NewProgram()
+	// errors are printed by main, and are about the configuration rather
+	// than how the command was used
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := programOptions(ui.LoadStore)
+		if err != nil {
+			return err
+		}
+
+		p := ui.NewProgram(opts...)
 
 		if _, err := p.Run(); err != nil {
 			panic(err)
 		}
+		return nil
 	},
 }
 
+var serveAddr string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the tracker and share the initiative order over HTTP",
+	Long: `Run the tracker and share the initiative order over HTTP.
+
+The current encounter is served as a self-refreshing web page at / and as
+JSON at /state.json, updated as turns are advanced in the terminal.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := programOptions(ui.LoadStore)
+		if err != nil {
+			return err
+		}
+
+		l, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+
+		s := server.New()
+		p := ui.NewProgram(append(opts, ui.WithEncounterObserver(s.Publish))...)
+
+		// The tracker quits when the server stops serving, so that the error
+		// is reported rather than the page silently going stale.
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Serve(l)
+			p.Quit()
+		}()
+
+		if _, err := p.Run(); err != nil {
+			panic(err)
+		}
+
+		select {
+		case err := <-errs:
+			return fmt.Errorf("serving over HTTP: %w", err)
+		default:
+			return nil
+		}
+	},
+}
+
+var (
+	sshAddr             string
+	sshHostKey          string
+	sshDMKeys           string
+	sshPlayerKeys       string
+	sshAnonymousPlayers bool
+)
+
+var sshCmd = &cobra.Command{
+	Use:   "ssh",
+	Short: "Serve the tracker over SSH",
+	Long: `Serve the tracker over SSH.
+
+All sessions share one tracker. A session authenticated with a key listed in
+--dm-keys gets the read-write tracker. Sessions authenticated with a key
+listed in --player-keys get a read-only view of the current encounter, as
+does anyone at all with --anonymous-players. Everyone else is turned away.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := programOptions(ui.LoadStore)
+		if err != nil {
+			return err
+		}
+
+		access := server.SSHAccess{Anonymous: sshAnonymousPlayers}
+		if access.DMKeys, err = server.LoadAuthorizedKeys(sshDMKeys); err != nil {
+			return err
+		}
+		if sshPlayerKeys != "" {
+			if access.PlayerKeys, err = server.LoadAuthorizedKeys(sshPlayerKeys); err != nil {
+				return err
+			}
+		}
+
+		if sshHostKey == "" {
+			dir, err := config.Dir()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(dir, 0o700); err != nil {
+				return err
+			}
+			sshHostKey = filepath.Join(dir, "ssh_host_ed25519")
+		}
+
+		// Sessions render with the server's default renderer, so don't let
+		// the server's own output decide whether they get colors.
+		lipgloss.SetColorProfile(termenv.ANSI256)
+
+		s, err := server.NewSSHServer(sshAddr, sshHostKey, access, opts...)
+		if err != nil {
+			return err
+		}
+
+		done := make(chan os.Signal, 1)
+		signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.ListenAndServe()
+		}()
+		fmt.Printf("Serving the tracker over SSH on %s\n", sshAddr)
+
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ssh.ErrServerClosed) {
+				return err
+			}
+		case <-done:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return s.Shutdown(ctx)
+	},
+}
+
+var runJSON bool
+
+var runCmd = &cobra.Command{
+	Use:   "run [file]",
+	Short: "Run an encounter from a script of commands",
+	Long: `Run an encounter from a script of commands, read from the file or from
+stdin, printing the initiative order after each command.
+
+Commands are one per line, with names containing spaces in double quotes:
+
+  add <name> [x<count>] [+<modifier>] [init <initiative>]   add a party character or monsters
+  next                                        start the encounter, then next turn
+  prev                                        go back a turn
+  dmg <target> <amount> [type] [crit] [from <source>]
+  heal <target> <amount> [from <source>]
+  hp <target> <hp> [max hp]                   track hit points
+  cond <target> <condition> [value]           toggle a condition
+  end                                         end the encounter
+
+Monsters are numbered on from those already in the encounter with the same
+name, so that adding Goblin after Goblin x3 adds Goblin 4. Lines starting
+with # are comments. Nothing is saved.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := programOptions(ui.ReadStore)
+		if err != nil {
+			return err
+		}
+
+		var r io.Reader = os.Stdin
+		name := "stdin"
+		if len(args) == 1 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r, name = f, args[0]
+		}
+
+		script := ui.NewScript(opts...)
+		scanner := bufio.NewScanner(r)
+		for line := 1; scanner.Scan(); line++ {
+			command := strings.TrimSpace(scanner.Text())
+			if command == "" || strings.HasPrefix(command, "#") {
+				continue
+			}
+			if err := script.Run(command); err != nil {
+				return fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+
+			if runJSON {
+				data, err := json.Marshal(server.NewState(script.Encounter))
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				continue
+			}
+			fmt.Printf("$ %s\n%s\n", command, script.State())
+		}
+		return scanner.Err()
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&dataFile, "data", "d", "", "path to the party and history data file (default in the user config directory)")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "path to the configuration file (default in the user config directory)")
+
+	serveCmd.Flags().StringVarP(&serveAddr, "addr", "a", ":8080", "address to serve the initiative order on")
+	rootCmd.AddCommand(serveCmd)
+
+	sshCmd.Flags().StringVarP(&sshAddr, "addr", "a", ":23234", "address to listen for SSH connections on")
+	sshCmd.Flags().StringVar(&sshHostKey, "host-key", "", "path to the server's host key, generated if missing (default in the user config directory)")
+	sshCmd.Flags().StringVar(&sshDMKeys, "dm-keys", "", "authorized_keys file listing the keys allowed to run the tracker")
+	sshCmd.MarkFlagRequired("dm-keys")
+	sshCmd.Flags().StringVar(&sshPlayerKeys, "player-keys", "", "authorized_keys file listing the keys allowed to watch the encounter")
+	sshCmd.Flags().BoolVar(&sshAnonymousPlayers, "anonymous-players", false, "let anyone who can reach the server watch the encounter, without a key")
+	rootCmd.AddCommand(sshCmd)
+
+	runCmd.Flags().BoolVar(&runJSON, "json", false, "print the initiative order as JSON, one line per command")
+	rootCmd.AddCommand(runCmd)
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Printf("Error: %v", err)